@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"boxy/internal/history"
+	"boxy/internal/manager"
+)
+
+const usage = `Usage:
+  boxy                  start the interactive UI
+  boxy owns <path>...   show which package owns a file or command
+  boxy history [--since 7d|2006-01-02] [-v]
+                        list package changes, by boxy and the managers
+  boxy snapshot save|show|restore|delete <name>
+  boxy snapshot list    save and roll back the installed packages, holds
+                        and bookmarks`
+
+// runSubcommand handles the non-interactive "boxy <command>" forms and
+// returns the process exit code. Commands about one manager use the
+// preferred one, and the user-level tool installers alongside it.
+func runSubcommand(managers []manager.PackageManager, name string, args []string) int {
+	switch name {
+	case "owns":
+		return runOwns(ownsManagers(managers), args)
+	case "history":
+		return runHistory(managers, args)
+	case "snapshot":
+		return runSnapshot(managers, args)
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n%s\n", name, usage)
+	return 2
+}
+
+// ownsManagers is the preferred manager followed by the user-level ones,
+// which own the commands in ~/.local/bin
+func ownsManagers(managers []manager.PackageManager) []manager.PackageManager {
+	owners := []manager.PackageManager{managers[0]}
+	for _, mgr := range managers[1:] {
+		if manager.IsUserLevel(mgr) {
+			owners = append(owners, mgr)
+		}
+	}
+	return owners
+}
+
+func runOwns(managers []manager.PackageManager, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: owns needs at least one path\n\n%s\n", usage)
+		return 2
+	}
+
+	ctx := context.Background()
+	status := 0
+	for _, arg := range args {
+		path, err := resolveOwnsPath(arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", arg, err)
+			status = 1
+			continue
+		}
+		owner, mgr, err := findOwner(ctx, managers, path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			status = 1
+			continue
+		}
+		if mgr == managers[0] {
+			fmt.Printf("%s: %s\n", path, owner)
+		} else {
+			fmt.Printf("%s: %s (%s)\n", path, owner, mgr.Name())
+		}
+	}
+	return status
+}
+
+// findOwner asks each manager in turn, returning the first owner found or
+// the first manager's error
+func findOwner(ctx context.Context, managers []manager.PackageManager, path string) (string, manager.PackageManager, error) {
+	var firstErr error
+	for _, mgr := range managers {
+		owner, err := mgr.Owner(ctx, path)
+		if err == nil {
+			return owner, mgr, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return "", nil, firstErr
+}
+
+func runHistory(managers []manager.PackageManager, args []string) int {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	since := flags.String("since", "", "only changes after a date (2006-01-02) or this long ago (7d, 12h)")
+	verbose := flags.Bool("v", false, "list every changed package, and the command and output of boxy's operations")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	own, err := history.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading history: %v\n", err)
+		return 1
+	}
+	system := make([][]history.Entry, len(managers))
+	for i, mgr := range managers {
+		system[i] = manager.SystemHistory(mgr)
+	}
+	entries := history.Merge(own, system...)
+
+	if *since != "" {
+		start, err := parseSince(*since, time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		entries = history.Since(entries, start)
+	}
+
+	for _, e := range entries {
+		fmt.Println(e.Summary())
+		if !*verbose {
+			continue
+		}
+		for _, c := range e.Packages {
+			fmt.Printf("    %s\n", c)
+		}
+		if e.Command != "" {
+			fmt.Printf("    $ %s\n", e.Command)
+		}
+		if output := strings.TrimRight(e.Output, "\n"); output != "" {
+			for _, line := range strings.Split(output, "\n") {
+				fmt.Printf("    | %s\n", line)
+			}
+		}
+	}
+	return 0
+}
+
+// parseSince reads --since as a date or a duration before now. Durations
+// also take days, which time.ParseDuration doesn't.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("--since wants a date like 2006-01-02 or a duration like 7d, got %q", s)
+	}
+	return now.Add(-d), nil
+}
+
+// resolveOwnsPath turns an argument into an absolute path. Bare command
+// names that don't exist in the current directory are looked up on PATH,
+// so "boxy owns rg" works like "boxy owns $(which rg)".
+func resolveOwnsPath(arg string) (string, error) {
+	if !strings.ContainsRune(arg, filepath.Separator) {
+		if _, err := os.Stat(arg); os.IsNotExist(err) {
+			return exec.LookPath(arg)
+		}
+	}
+	return filepath.Abs(arg)
+}
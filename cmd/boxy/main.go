@@ -12,20 +12,32 @@ import (
 )
 
 func main() {
-	mgr := manager.Detect()
-	if mgr == nil {
-		fmt.Fprintln(os.Stderr, "Error: No supported package manager found (brew or apt)")
+	managers := manager.DetectAll()
+	if len(managers) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: No supported package manager found (brew, apt, pipx, uv, npm, cargo, go or a boxy-manager-* plugin)")
 		os.Exit(1)
 	}
 
+	if len(os.Args) > 1 {
+		os.Exit(runSubcommand(managers, os.Args[1], os.Args[2:]))
+	}
+
 	cfg, err := config.Load()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 		os.Exit(1)
 	}
 
-	m := tui.NewModel(mgr, cfg)
-	p := tea.NewProgram(m, tea.WithAltScreen())
+	cached := make([]manager.PackageManager, len(managers))
+	for i, mgr := range managers {
+		cached[i] = manager.WithCache(mgr)
+	}
+	m, err := tui.NewModel(cached, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error in config: %v\n", err)
+		os.Exit(1)
+	}
+	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion())
 
 	if _, err := p.Run(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
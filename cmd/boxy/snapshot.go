@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"boxy/internal/config"
+	"boxy/internal/manager"
+	"boxy/internal/snapshot"
+)
+
+func runSnapshot(managers []manager.PackageManager, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: snapshot needs save, list, show, restore or delete\n\n%s\n", usage)
+		return 2
+	}
+	if args[0] == "list" {
+		return listSnapshots()
+	}
+
+	flags := flag.NewFlagSet("snapshot "+args[0], flag.ContinueOnError)
+	yes := flags.Bool("y", false, "restore without asking")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "Error: snapshot %s needs a name\n\n%s\n", args[0], usage)
+		return 2
+	}
+	name := flags.Arg(0)
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		return 1
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "save":
+		s, err := snapshot.Take(ctx, name, managers, cfg)
+		if err == nil {
+			err = s.Save()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Saved snapshot %s: %s\n", name, s.Summary())
+		return 0
+
+	case "delete":
+		if err := snapshot.Delete(name); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Deleted snapshot %s\n", name)
+		return 0
+
+	case "show", "restore":
+		s, err := snapshot.Load(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		plan, err := snapshot.Compare(ctx, s, managers, cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if plan.Empty() {
+			fmt.Printf("Already matches snapshot %s\n", name)
+			return 0
+		}
+		for _, line := range plan.Lines() {
+			fmt.Println(line)
+		}
+		if args[0] == "show" {
+			return 0
+		}
+		if !*yes && !confirm(fmt.Sprintf("Restore snapshot %s (%s)?", name, plan.Summary())) {
+			return 1
+		}
+		return restoreSnapshot(ctx, plan, cfg)
+	}
+
+	fmt.Fprintf(os.Stderr, "Unknown snapshot command: %s\n\n%s\n", args[0], usage)
+	return 2
+}
+
+func listSnapshots() int {
+	snapshots, err := snapshot.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	for _, s := range snapshots {
+		fmt.Printf("%-20s %s  %s\n", s.Name, s.Created.Local().Format("2006-01-02 15:04"), s.Summary())
+	}
+	return 0
+}
+
+// confirm asks a yes/no question on the terminal, defaulting to no
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// restoreSnapshot runs the plan's commands in the terminal, so sudo can
+// ask for a password, and stops at the first that fails. Each is recorded
+// in the history, without its output. Unlike a restore from the TUI, it
+// doesn't run the config's hooks.
+func restoreSnapshot(ctx context.Context, plan snapshot.Plan, cfg *config.Config) int {
+	for _, op := range plan.Ops() {
+		err := runRecorded(ctx, op.Manager, op.Action, op.Pkgs)
+		if err != nil && op.Fallback != nil {
+			fmt.Fprintf(os.Stderr, "%v; installing the missing packages at the current versions\n", err)
+			if fallbackErr := runRecorded(ctx, op.Manager, op.Action, op.Fallback); fallbackErr != nil {
+				err = fallbackErr
+			} else {
+				err = op.FallbackError(err)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	if plan.Bookmarks {
+		cfg.Packages = plan.Snapshot.Bookmarks
+		if err := cfg.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving bookmarks: %v\n", err)
+			return 1
+		}
+	}
+	if err := plan.UnpinnedError(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Restored snapshot %s\n", plan.Snapshot.Name)
+	return 0
+}
+
+// runRecorded runs one of the plan's commands in the terminal and records
+// it in the history
+func runRecorded(ctx context.Context, mgr manager.PackageManager, action string, pkgs []string) error {
+	rec := manager.RunRecorded(ctx, mgr, action, pkgs, func(cmd *exec.Cmd) (string, error) {
+		fmt.Printf("$ %s\n", strings.Join(cmd.Args, " "))
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		return "", cmd.Run()
+	})
+	if rec.HistoryErr != nil {
+		fmt.Fprintf(os.Stderr, "Not recorded in history: %v\n", rec.HistoryErr)
+	}
+	return rec.Err
+}
@@ -3,12 +3,142 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Packages []string `yaml:"packages"`
+	Packages []Package `yaml:"packages"`
+	// Columns lists the package list columns in display order. Empty means
+	// name, version and description.
+	Columns []string `yaml:"columns,omitempty"`
+	Keys    Keys     `yaml:"keys,omitempty"`
+	// Theme names a built-in theme (auto, dark, light, high-contrast) or
+	// one of Themes. Empty means auto.
+	Theme string `yaml:"theme,omitempty"`
+	// Themes are user palettes: color role -> color, plus an optional
+	// "base" theme to start from.
+	Themes map[string]map[string]string `yaml:"themes,omitempty"`
+	// Hooks run around every install and uninstall, before and after the
+	// bookmarks' own
+	Hooks Hooks `yaml:"hooks,omitempty"`
+}
+
+// Package is a bookmarked package, written as a bare name or, to tag it,
+// note why it's there, give it hooks or name its manager, as a mapping:
+// `{name: kubectl, tags: [k8s, work], note: needed for the payments repo}`.
+type Package struct {
+	Name string `yaml:"name"`
+	// Manager is the user-level tool the package comes from, like pipx or
+	// npm. It's empty for the system manager's packages, which stay
+	// bookmarked whichever of apt, brew or a plugin is in use.
+	Manager string   `yaml:"manager,omitempty"`
+	Tags    []string `yaml:"tags,omitempty"`
+	Note    string   `yaml:"note,omitempty"`
+	Hooks   `yaml:",inline"`
+}
+
+// Hooks are shell commands run before and after installing or
+// uninstalling, e.g. `post_install: systemctl --user enable --now foo`.
+type Hooks struct {
+	PreInstall    string `yaml:"pre_install,omitempty"`
+	PostInstall   string `yaml:"post_install,omitempty"`
+	PreUninstall  string `yaml:"pre_uninstall,omitempty"`
+	PostUninstall string `yaml:"post_uninstall,omitempty"`
+}
+
+// Hook returns the command for a phase ("pre_install", "post_install",
+// "pre_uninstall" or "post_uninstall"), or "" if there is none.
+func (h Hooks) Hook(phase string) string {
+	switch phase {
+	case "pre_install":
+		return h.PreInstall
+	case "post_install":
+		return h.PostInstall
+	case "pre_uninstall":
+		return h.PreUninstall
+	case "post_uninstall":
+		return h.PostUninstall
+	}
+	return ""
+}
+
+func (h Hooks) multiline() bool {
+	for _, cmd := range []string{h.PreInstall, h.PostInstall, h.PreUninstall, h.PostUninstall} {
+		if strings.Contains(cmd, "\n") {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Package) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*p = Package{Name: node.Value}
+		return nil
+	}
+	type plain Package
+	return node.Decode((*plain)(p))
+}
+
+// MarshalYAML keeps each bookmark on one line, so long lists stay
+// readable. Notes and hooks spanning several lines are written as a block
+// instead.
+func (p Package) MarshalYAML() (interface{}, error) {
+	if p.Manager == "" && len(p.Tags) == 0 && p.Note == "" && p.Hooks == (Hooks{}) {
+		return p.Name, nil
+	}
+	type plain Package
+	var node yaml.Node
+	if err := node.Encode(plain(p)); err != nil {
+		return nil, err
+	}
+	if !strings.Contains(p.Note, "\n") && !p.Hooks.multiline() {
+		node.Style = yaml.FlowStyle
+	}
+	return &node, nil
+}
+
+func (p Package) HasTag(tag string) bool {
+	for _, t := range p.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Keys customizes key bindings: a preset ("default", "vim" or "emacs") and
+// per-action overrides on top of it, e.g. `install: [i, ctrl+i]` or
+// `quit: x`.
+type Keys struct {
+	Preset   string             `yaml:"preset,omitempty"`
+	Bindings map[string]KeyList `yaml:",inline"`
+}
+
+// KeyList is one or more keys, written as a single key or a list.
+type KeyList []string
+
+func (k *KeyList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*k = KeyList{node.Value}
+		return nil
+	}
+	var keys []string
+	if err := node.Decode(&keys); err != nil {
+		return err
+	}
+	*k = keys
+	return nil
+}
+
+func (k KeyList) MarshalYAML() (interface{}, error) {
+	if len(k) == 1 {
+		return k[0], nil
+	}
+	return []string(k), nil
 }
 
 func DefaultPath() (string, error) {
@@ -28,7 +158,7 @@ func Load() (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return &Config{Packages: []string{}}, nil
+			return &Config{Packages: []Package{}}, nil
 		}
 		return nil, err
 	}
@@ -39,7 +169,7 @@ func Load() (*Config, error) {
 	}
 
 	if cfg.Packages == nil {
-		cfg.Packages = []string{}
+		cfg.Packages = []Package{}
 	}
 
 	return &cfg, nil
@@ -64,35 +194,112 @@ func (c *Config) Save() error {
 	return os.WriteFile(path, data, 0644)
 }
 
-func (c *Config) IsBookmarked(pkg string) bool {
-	for _, p := range c.Packages {
-		if p == pkg {
-			return true
+// find returns the index of pkg's bookmark, or -1. mgr is the bookmark's
+// manager as in Package.Manager, as for the methods below.
+func (c *Config) find(mgr, pkg string) int {
+	for i, p := range c.Packages {
+		if p.Manager == mgr && p.Name == pkg {
+			return i
 		}
 	}
-	return false
+	return -1
+}
+
+func (c *Config) IsBookmarked(mgr, pkg string) bool {
+	return c.find(mgr, pkg) >= 0
 }
 
-func (c *Config) AddBookmark(pkg string) {
-	if !c.IsBookmarked(pkg) {
-		c.Packages = append(c.Packages, pkg)
+func (c *Config) AddBookmark(mgr, pkg string) {
+	if !c.IsBookmarked(mgr, pkg) {
+		c.Packages = append(c.Packages, Package{Name: pkg, Manager: mgr})
 	}
 }
 
-func (c *Config) RemoveBookmark(pkg string) {
-	for i, p := range c.Packages {
-		if p == pkg {
-			c.Packages = append(c.Packages[:i], c.Packages[i+1:]...)
-			return
-		}
+func (c *Config) RemoveBookmark(mgr, pkg string) {
+	if i := c.find(mgr, pkg); i >= 0 {
+		c.Packages = append(c.Packages[:i], c.Packages[i+1:]...)
 	}
 }
 
-func (c *Config) ToggleBookmark(pkg string) bool {
-	if c.IsBookmarked(pkg) {
-		c.RemoveBookmark(pkg)
+func (c *Config) ToggleBookmark(mgr, pkg string) bool {
+	if c.IsBookmarked(mgr, pkg) {
+		c.RemoveBookmark(mgr, pkg)
 		return false
 	}
-	c.AddBookmark(pkg)
+	c.AddBookmark(mgr, pkg)
 	return true
 }
+
+// Tags returns the tags of a bookmarked package
+func (c *Config) Tags(mgr, pkg string) []string {
+	if i := c.find(mgr, pkg); i >= 0 {
+		return c.Packages[i].Tags
+	}
+	return nil
+}
+
+// SetTags replaces the tags of pkg, bookmarking it if it isn't already.
+// Tags are lowercased and deduplicated.
+func (c *Config) SetTags(mgr, pkg string, tags []string) {
+	seen := make(map[string]bool)
+	var clean []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			clean = append(clean, tag)
+		}
+	}
+	c.AddBookmark(mgr, pkg)
+	c.Packages[c.find(mgr, pkg)].Tags = clean
+}
+
+// Note returns the note on a bookmarked package
+func (c *Config) Note(mgr, pkg string) string {
+	if i := c.find(mgr, pkg); i >= 0 {
+		return c.Packages[i].Note
+	}
+	return ""
+}
+
+// PackageHooks returns the hooks set on pkg's bookmark, if any
+func (c *Config) PackageHooks(mgr, pkg string) Hooks {
+	if i := c.find(mgr, pkg); i >= 0 {
+		return c.Packages[i].Hooks
+	}
+	return Hooks{}
+}
+
+// SetNote replaces the note on pkg, bookmarking it if it isn't already. An
+// empty note removes it.
+func (c *Config) SetNote(mgr, pkg, note string) {
+	c.AddBookmark(mgr, pkg)
+	c.Packages[c.find(mgr, pkg)].Note = strings.TrimSpace(note)
+}
+
+// AllTags returns every tag in use, sorted
+func (c *Config) AllTags() []string {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, p := range c.Packages {
+		for _, tag := range p.Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Tagged returns the bookmarks carrying tag
+func (c *Config) Tagged(tag string) []Package {
+	var tagged []Package
+	for _, p := range c.Packages {
+		if p.HasTag(tag) {
+			tagged = append(tagged, p)
+		}
+	}
+	return tagged
+}
@@ -0,0 +1,260 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Entry is one change to the installed packages: an operation boxy ran,
+// or one read from a package manager's own logs.
+type Entry struct {
+	Time     time.Time `json:"time"`
+	Source   string    `json:"source"` // "boxy", or the manager whose log it came from
+	Manager  string    `json:"manager"`
+	Action   string    `json:"action"` // install, uninstall, upgrade, cleanup or undo
+	Packages []Change  `json:"packages"`
+	Command  string    `json:"command,omitempty"`
+	ExitCode int       `json:"exit_code"`
+	Error    string    `json:"error,omitempty"`
+	Output   string    `json:"output,omitempty"`
+	// Undoes is the time of the operation this one reversed, for undos
+	Undoes *time.Time `json:"undoes,omitempty"`
+	// ChangesUnknown is set when the installed packages couldn't be listed
+	// around the operation, so Packages only names the ones asked for.
+	// Such an operation can't be undone.
+	ChangesUnknown bool `json:"changes_unknown,omitempty"`
+}
+
+// Change is one package's version before and after an operation. An empty
+// Before means it was newly installed, an empty After that it was removed.
+type Change struct {
+	Name   string `json:"name"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+func (c Change) String() string {
+	switch {
+	case c.Before == "" && c.After == "":
+		return c.Name
+	case c.Before == c.After:
+		return fmt.Sprintf("%s %s (unchanged)", c.Name, c.After)
+	case c.Before == "":
+		return fmt.Sprintf("+%s %s", c.Name, c.After)
+	case c.After == "":
+		return fmt.Sprintf("-%s %s", c.Name, c.Before)
+	}
+	return fmt.Sprintf("%s %s → %s", c.Name, c.Before, c.After)
+}
+
+// Failed reports whether the operation didn't succeed
+func (e Entry) Failed() bool {
+	return e.ExitCode != 0 || e.Error != ""
+}
+
+// StateDir returns boxy's directory under the XDG state dir, which holds
+// the history and snapshots.
+func StateDir() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "boxy"), nil
+}
+
+// Path returns where boxy records its operations.
+func Path() (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history.jsonl"), nil
+}
+
+// Append records e as one line of the history file
+func Append(e Entry) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load reads the operations boxy has recorded. Lines that don't parse (a
+// write cut short, say) are skipped.
+func Load() ([]Entry, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err == nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// sameOperation is how far apart boxy's record and the manager's log of
+// the same operation may be timestamped
+const sameOperation = 2 * time.Minute
+
+// Merge combines boxy's own entries with those read from the managers'
+// logs, newest first. Operations boxy ran show up in both; the manager's
+// copy is dropped in favor of boxy's, which carries the output.
+func Merge(own []Entry, system ...[]Entry) []Entry {
+	merged := append([]Entry(nil), own...)
+	for _, list := range system {
+		for _, e := range list {
+			if !recorded(own, e) {
+				merged = append(merged, e)
+			}
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Time.After(merged[j].Time)
+	})
+	return merged
+}
+
+// recorded reports whether a system log entry is one of boxy's operations
+func recorded(own []Entry, e Entry) bool {
+	for _, o := range own {
+		if o.Manager != e.Manager || o.Time.Sub(e.Time).Abs() > sameOperation {
+			continue
+		}
+		for _, c := range e.Packages {
+			if o.touches(c.Name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (e Entry) touches(pkg string) bool {
+	for _, c := range e.Packages {
+		if c.Name == pkg {
+			return true
+		}
+	}
+	return false
+}
+
+// Changed reports whether c's version differs before and after
+func (c Change) Changed() bool {
+	return c.Before != c.After
+}
+
+// LastUndoable returns the most recent of boxy's operations with one of
+// managers that changed something known and hasn't been undone. Undos are
+// skipped, so undoing repeatedly walks back through the history; an undo
+// that failed, even after some of its steps went through, leaves its
+// operation undoable. entries are newest first.
+func LastUndoable(entries []Entry, managers ...string) (Entry, bool) {
+	wanted := make(map[string]bool)
+	for _, mgr := range managers {
+		wanted[mgr] = true
+	}
+	undone := make(map[time.Time]bool)
+	for _, e := range entries {
+		if e.Source != "boxy" || !wanted[e.Manager] || e.ChangesUnknown {
+			continue
+		}
+		if e.Undoes != nil {
+			if !e.Failed() {
+				undone[e.Undoes.UTC()] = true
+			}
+			continue
+		}
+		if undone[e.Time.UTC()] {
+			continue
+		}
+		for _, c := range e.Packages {
+			if c.Changed() {
+				return e, true
+			}
+		}
+	}
+	return Entry{}, false
+}
+
+// Since returns the entries at or after t
+func Since(entries []Entry, t time.Time) []Entry {
+	var recent []Entry
+	for _, e := range entries {
+		if !e.Time.Before(t) {
+			recent = append(recent, e)
+		}
+	}
+	return recent
+}
+
+// summaryChanges is how many changed packages Summary names
+const summaryChanges = 5
+
+// Summary is a one-line description of e for listings
+func (e Entry) Summary() string {
+	var changes []string
+	for i, c := range e.Packages {
+		if i == summaryChanges {
+			changes = append(changes, fmt.Sprintf("and %d more", len(e.Packages)-summaryChanges))
+			break
+		}
+		changes = append(changes, c.String())
+	}
+	what := strings.Join(changes, ", ")
+	if what == "" {
+		what = "no changes"
+	}
+	line := fmt.Sprintf("%s  %-5s %-9s %s", e.Time.Local().Format("2006-01-02 15:04"), e.Manager, e.Action, what)
+	if e.Source != "boxy" {
+		line += fmt.Sprintf(" (%s log)", e.Source)
+	}
+	if e.ChangesUnknown {
+		line += " (changes unknown)"
+	}
+	if e.Failed() {
+		line += fmt.Sprintf(" [failed, exit %d]", e.ExitCode)
+	}
+	return line
+}
@@ -0,0 +1,165 @@
+package history
+
+import (
+	"testing"
+	"time"
+)
+
+var base = time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC)
+
+func at(minutes int) time.Time {
+	return base.Add(time.Duration(minutes) * time.Minute)
+}
+
+func TestMerge(t *testing.T) {
+	own := []Entry{
+		{Time: at(0), Source: "boxy", Manager: "apt", Action: "install", Packages: []Change{{Name: "jq", After: "1.6"}}},
+		{Time: at(60), Source: "boxy", Manager: "brew", Action: "install", Packages: []Change{{Name: "wget", After: "1.21"}}},
+	}
+	apt := []Entry{
+		// boxy's install of jq, logged by apt a few seconds later
+		{Time: at(0).Add(5 * time.Second), Source: "apt", Manager: "apt", Action: "install", Packages: []Change{{Name: "libjq1", After: "1.6"}, {Name: "jq", After: "1.6"}}},
+		// the same package installed again, outside boxy, much later
+		{Time: at(30), Source: "apt", Manager: "apt", Action: "install", Packages: []Change{{Name: "jq", After: "1.6"}}},
+		// another package at the same time as boxy's install
+		{Time: at(1), Source: "apt", Manager: "apt", Action: "install", Packages: []Change{{Name: "curl", After: "7.88"}}},
+	}
+	brew := []Entry{
+		// brew logs nothing boxy ran
+		{Time: at(-10), Source: "brew", Manager: "brew", Action: "install", Packages: []Change{{Name: "git", After: "2.44"}}},
+	}
+
+	tests := []struct {
+		name   string
+		own    []Entry
+		system [][]Entry
+		want   []string // Source and first package of each entry, in order
+	}{
+		{
+			name:   "drops the manager's copies of boxy's operations",
+			own:    own,
+			system: [][]Entry{apt, brew},
+			want:   []string{"boxy wget", "apt jq", "apt curl", "boxy jq", "brew git"},
+		},
+		{
+			name:   "no system logs",
+			own:    own,
+			system: nil,
+			want:   []string{"boxy wget", "boxy jq"},
+		},
+		{
+			name:   "no own entries",
+			own:    nil,
+			system: [][]Entry{brew, apt},
+			want:   []string{"apt jq", "apt curl", "apt libjq1", "brew git"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := Merge(tt.own, tt.system...)
+			var got []string
+			for _, e := range merged {
+				got = append(got, e.Source+" "+e.Packages[0].Name)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Merge = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Merge = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeLeavesOwnAlone(t *testing.T) {
+	own := make([]Entry, 1, 4)
+	own[0] = Entry{Time: at(0), Source: "boxy", Manager: "apt", Packages: []Change{{Name: "jq"}}}
+	Merge(own, []Entry{{Time: at(10), Source: "apt", Manager: "apt", Packages: []Change{{Name: "curl"}}}})
+	if own[:2][1].Source != "" {
+		t.Errorf("Merge appended into own's backing array")
+	}
+}
+
+func TestLastUndoable(t *testing.T) {
+	undoes := func(minutes int) *time.Time {
+		when := at(minutes)
+		return &when
+	}
+	install := Entry{Time: at(0), Source: "boxy", Manager: "apt", Action: "install", Packages: []Change{{Name: "jq", After: "1.6"}}}
+	upgrade := Entry{Time: at(10), Source: "boxy", Manager: "apt", Action: "upgrade", Packages: []Change{{Name: "curl", Before: "7.88.1-10", After: "7.88.1-11"}}}
+	unchanged := Entry{Time: at(20), Source: "boxy", Manager: "apt", Action: "install", Packages: []Change{{Name: "jq", Before: "1.6", After: "1.6"}}, ExitCode: 100}
+	unknown := Entry{Time: at(30), Source: "boxy", Manager: "apt", Action: "install", Packages: []Change{{Name: "ncdu"}}, ChangesUnknown: true}
+	logged := Entry{Time: at(40), Source: "apt", Manager: "apt", Action: "install", Packages: []Change{{Name: "htop", After: "3.2"}}}
+	brew := Entry{Time: at(50), Source: "boxy", Manager: "brew", Action: "install", Packages: []Change{{Name: "wget", After: "1.21"}}}
+	undoUpgrade := Entry{Time: at(60), Source: "boxy", Manager: "apt", Action: "install", Packages: []Change{{Name: "curl", Before: "7.88.1-11", After: "7.88.1-10"}}, Undoes: undoes(10)}
+	failedUndo := Entry{Time: at(60), Source: "boxy", Manager: "apt", Action: "install", Undoes: undoes(10), ExitCode: 100, Error: "exit status 100"}
+
+	tests := []struct {
+		name     string
+		entries  []Entry // newest first
+		managers []string
+		want     *Entry
+	}{
+		{
+			name:     "most recent change",
+			entries:  []Entry{upgrade, install},
+			managers: []string{"apt"},
+			want:     &upgrade,
+		},
+		{
+			name:     "skips what changed nothing, is unknown or came from a log",
+			entries:  []Entry{logged, unknown, unchanged, upgrade, install},
+			managers: []string{"apt"},
+			want:     &upgrade,
+		},
+		{
+			name:     "only the given managers",
+			entries:  []Entry{brew, upgrade},
+			managers: []string{"apt"},
+			want:     &upgrade,
+		},
+		{
+			name:     "any of the given managers",
+			entries:  []Entry{brew, upgrade},
+			managers: []string{"apt", "brew"},
+			want:     &brew,
+		},
+		{
+			name:     "walks back past an undone operation",
+			entries:  []Entry{undoUpgrade, upgrade, install},
+			managers: []string{"apt"},
+			want:     &install,
+		},
+		{
+			name:     "a failed undo leaves the operation undoable",
+			entries:  []Entry{failedUndo, upgrade, install},
+			managers: []string{"apt"},
+			want:     &upgrade,
+		},
+		{
+			name:     "nothing to undo",
+			entries:  []Entry{undoUpgrade, upgrade, unknown},
+			managers: []string{"apt"},
+		},
+		{
+			name:    "no managers",
+			entries: []Entry{upgrade},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := LastUndoable(tt.entries, tt.managers...)
+			if tt.want == nil {
+				if ok {
+					t.Errorf("LastUndoable = %s, want none", got.Summary())
+				}
+				return
+			}
+			if !ok || !got.Time.Equal(tt.want.Time) || got.Action != tt.want.Action {
+				t.Errorf("LastUndoable = %s, %v, want %s", got.Summary(), ok, tt.want.Summary())
+			}
+		})
+	}
+}
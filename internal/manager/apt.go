@@ -2,14 +2,24 @@ package manager
 
 import (
 	"bufio"
+	"bytes"
+	"compress/gzip"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 )
 
-type AptManager struct{}
+type AptManager struct {
+	indexOnce sync.Once
+	idx       *Index
+	indexErr  error
+}
 
 func (a *AptManager) Name() string {
 	return "apt"
@@ -21,6 +31,10 @@ func (a *AptManager) IsAvailable() bool {
 }
 
 func (a *AptManager) Search(ctx context.Context, query string) ([]PackageInfo, error) {
+	if idx, err := a.index(); err == nil && idx.Len() > 0 {
+		return idx.Search(query), nil
+	}
+
 	cmd := exec.CommandContext(ctx, "apt-cache", "search", query)
 	output, err := cmd.Output()
 	if err != nil {
@@ -47,13 +61,27 @@ func (a *AptManager) Search(ctx context.Context, query string) ([]PackageInfo, e
 	return results, scanner.Err()
 }
 
-func (a *AptManager) Command(ctx context.Context, action string, pkg string) *exec.Cmd {
+func (a *AptManager) Command(ctx context.Context, action string, pkgs ...string) *exec.Cmd {
 	var args []string
 	switch action {
 	case "install":
-		args = []string{"apt-get", "install", "-y", pkg}
+		args = []string{"apt-get", "install", "-y"}
+		for _, pkg := range pkgs {
+			if strings.Contains(pkg, "=") {
+				// A pinned version may be older than the installed one
+				args = append(args, "--allow-downgrades")
+				break
+			}
+		}
+		args = append(args, pkgs...)
 	case "uninstall":
-		args = []string{"apt-get", "remove", "-y", pkg}
+		args = append([]string{"apt-get", "remove", "-y"}, pkgs...)
+	case "upgrade":
+		args = append([]string{"apt-get", "install", "--only-upgrade", "-y"}, pkgs...)
+	case "hold", "unhold", "manual", "auto":
+		args = append([]string{"apt-mark", action}, pkgs...)
+	case "cleanup":
+		args = []string{"apt-get", "autoremove", "-y"}
 	}
 	return exec.CommandContext(ctx, "sudo", args...)
 }
@@ -62,6 +90,12 @@ func (a *AptManager) NeedsSudo() bool {
 	return true
 }
 
+// StatePaths returns the files dpkg and apt rewrite when packages are
+// installed or removed, marked manual/auto, or the package lists refreshed.
+func (a *AptManager) StatePaths() []string {
+	return []string{"/var/lib/dpkg/status", "/var/lib/apt/extended_states", "/var/lib/apt/lists"}
+}
+
 func (a *AptManager) Install(ctx context.Context, packages ...string) error {
 	args := append([]string{"apt-get", "install", "-y"}, packages...)
 	cmd := exec.CommandContext(ctx, "sudo", args...)
@@ -101,54 +135,231 @@ func (a *AptManager) IsInstalled(ctx context.Context, pkg string) (bool, error)
 }
 
 func (a *AptManager) GetInfo(ctx context.Context, pkg string) (PackageInfo, error) {
-	cmd := exec.CommandContext(ctx, "apt-cache", "show", pkg)
-	output, err := cmd.Output()
+	// apt-cache show prints one stanza per available version. A package that
+	// is installed but no longer in any list fails here, so fall through to
+	// the dpkg status stanza before giving up.
+	var available []controlStanza
+	output, showErr := exec.CommandContext(ctx, "apt-cache", "show", pkg).Output()
+	if showErr == nil {
+		available, showErr = parseControl(strings.NewReader(string(output)))
+	}
+
+	installed := a.installedStanza(ctx, pkg)
+	if installed == nil && len(available) == 0 {
+		if showErr == nil {
+			showErr = fmt.Errorf("no information for %s", pkg)
+		}
+		return PackageInfo{}, showErr
+	}
+
+	candidate := a.candidateVersion(ctx, pkg)
+
+	// Prefer the stanza describing what is on disk, then the candidate
+	// apt would install, then whatever apt-cache listed first.
+	primary := installed
+	if primary == nil {
+		for _, st := range available {
+			if st["Version"] == candidate {
+				primary = st
+				break
+			}
+		}
+	}
+	if primary == nil {
+		primary = available[0]
+	}
+
+	info := PackageInfo{
+		Name:             pkg,
+		Section:          primary["Section"],
+		Priority:         primary["Priority"],
+		Maintainer:       primary["Maintainer"],
+		Homepage:         primary["Homepage"],
+		InstalledSize:    primary.installedSize(),
+		Depends:          primary.list("Depends"),
+		Recommends:       primary.list("Recommends"),
+		Suggests:         primary.list("Suggests"),
+		Source:           primary["Source"],
+		CandidateVersion: candidate,
+	}
+	if name := primary["Package"]; name != "" {
+		info.Name = name
+	}
+	info.Description, info.LongDescription = primary.description()
+
+	// apt-cache often only has the Description-md5 when translations are
+	// not downloaded, so borrow the long description from another stanza.
+	if info.LongDescription == "" {
+		for _, st := range available {
+			if _, long := st.description(); long != "" {
+				info.LongDescription = long
+				break
+			}
+		}
+	}
+
+	if installed != nil {
+		info.Installed = true
+		info.InstalledVersion = installed["Version"]
+		info.Version = info.InstalledVersion
+	} else {
+		info.Version = primary["Version"]
+	}
+
+	return info, nil
+}
+
+// installedStanza returns the dpkg status stanza for pkg, or nil when the
+// package is not installed.
+func (a *AptManager) installedStanza(ctx context.Context, pkg string) controlStanza {
+	output, err := exec.CommandContext(ctx, "dpkg-query", "-s", pkg).Output()
 	if err != nil {
-		return PackageInfo{}, err
+		return nil
 	}
+	stanzas, err := parseControl(strings.NewReader(string(output)))
+	if err != nil || len(stanzas) == 0 {
+		return nil
+	}
+	if !strings.Contains(stanzas[0]["Status"], "install ok installed") {
+		return nil
+	}
+	return stanzas[0]
+}
 
-	info := PackageInfo{Name: pkg}
+// candidateVersion returns the version apt would install, as reported by
+// apt-cache policy. It is empty when there is no candidate.
+func (a *AptManager) candidateVersion(ctx context.Context, pkg string) string {
+	output, err := exec.CommandContext(ctx, "apt-cache", "policy", pkg).Output()
+	if err != nil {
+		return ""
+	}
 	scanner := bufio.NewScanner(strings.NewReader(string(output)))
 	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "Package: ") {
-			info.Name = strings.TrimPrefix(line, "Package: ")
-		} else if strings.HasPrefix(line, "Version: ") {
-			info.Version = strings.TrimPrefix(line, "Version: ")
-		} else if strings.HasPrefix(line, "Description: ") {
-			info.Description = strings.TrimPrefix(line, "Description: ")
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "Candidate: ") {
+			candidate := strings.TrimPrefix(line, "Candidate: ")
+			if candidate == "(none)" {
+				return ""
+			}
+			return candidate
 		}
 	}
-
-	installed, _ := a.IsInstalled(ctx, pkg)
-	info.Installed = installed
-
-	return info, scanner.Err()
+	return ""
 }
 
 func (a *AptManager) ListInstalled(ctx context.Context) ([]PackageInfo, error) {
-	cmd := exec.CommandContext(ctx, "dpkg-query", "-W", "-f=${Package}\n")
+	cmd := exec.CommandContext(ctx, "dpkg-query", "-W",
+		"-f=${db:Status-Abbrev}\t${Package}\t${binary:Package}\t${Version}\t${Installed-Size}\t${binary:Summary}\n")
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, err
 	}
 
+	times := aptPackageTimes()
+
 	var results []PackageInfo
 	scanner := bufio.NewScanner(strings.NewReader(string(output)))
 	for scanner.Scan() {
-		name := strings.TrimSpace(scanner.Text())
-		if name == "" {
+		fields := strings.SplitN(scanner.Text(), "\t", 6)
+		if len(fields) < 6 {
 			continue
 		}
-		results = append(results, PackageInfo{
-			Name:      name,
-			Installed: true,
-		})
+		// Skip removed packages that only left config files behind ("rc")
+		status := fields[0]
+		if len(status) < 2 || status[1] != 'i' {
+			continue
+		}
+		info := PackageInfo{
+			Name:             fields[1],
+			Version:          fields[3],
+			InstalledVersion: fields[3],
+			Description:      fields[5],
+			Installed:        true,
+			InstalledSize:    kibToBytes(fields[4]),
+		}
+
+		// dpkg rewrites the file list on every install or upgrade, so its
+		// mtime stands in for whatever the rotated logs no longer cover
+		t := times[info.Name]
+		if t.installed.IsZero() || t.updated.IsZero() {
+			if fi, err := os.Stat("/var/lib/dpkg/info/" + fields[2] + ".list"); err == nil {
+				if t.installed.IsZero() {
+					t.installed = fi.ModTime()
+				}
+				if t.updated.IsZero() {
+					t.updated = fi.ModTime()
+				}
+			}
+		}
+		info.InstallTime = t.installed
+		info.UpdateTime = t.updated
+
+		results = append(results, info)
 	}
 
 	return results, scanner.Err()
 }
 
+type packageTimes struct {
+	installed time.Time // last fresh install
+	updated   time.Time // last install or upgrade
+}
+
+// aptPackageTimes reads dpkg's logs for when each package was last freshly
+// installed and last changed version. The logs are rotated, so packages
+// installed long ago are missing.
+func aptPackageTimes() map[string]packageTimes {
+	times := make(map[string]packageTimes)
+	files, _ := filepath.Glob("/var/log/dpkg.log*")
+	for _, path := range files {
+		data, err := readMaybeGzip(path)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			// 2024-01-02 15:04:05 install pkg:amd64 <none> 1.0-1
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 6 || (fields[2] != "install" && fields[2] != "upgrade") {
+				continue
+			}
+			when, err := time.ParseInLocation("2006-01-02 15:04:05", fields[0]+" "+fields[1], time.Local)
+			if err != nil {
+				continue
+			}
+			name, _, _ := strings.Cut(fields[3], ":")
+			t := times[name]
+			if fields[2] == "install" && fields[4] == "<none>" && when.After(t.installed) {
+				t.installed = when
+			}
+			if when.After(t.updated) {
+				t.updated = when
+			}
+			times[name] = t
+		}
+	}
+	return times
+}
+
+// readMaybeGzip reads a file, transparently decompressing rotated .gz logs.
+func readMaybeGzip(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if !strings.HasSuffix(path, ".gz") {
+		return io.ReadAll(f)
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	return io.ReadAll(gz)
+}
+
 func (a *AptManager) ListManuallyInstalled(ctx context.Context) ([]PackageInfo, error) {
 	cmd := exec.CommandContext(ctx, "apt-mark", "showmanual")
 	output, err := cmd.Output()
@@ -171,3 +382,154 @@ func (a *AptManager) ListManuallyInstalled(ctx context.Context) ([]PackageInfo,
 
 	return results, scanner.Err()
 }
+
+func (a *AptManager) Files(ctx context.Context, pkg string) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "dpkg", "-L", pkg)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	// dpkg -L lists every directory the package touches; keep only files
+	var files []string
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	for scanner.Scan() {
+		path := strings.TrimSpace(scanner.Text())
+		if path == "" || path == "/." {
+			continue
+		}
+		if fi, err := os.Lstat(path); err == nil && fi.IsDir() {
+			continue
+		}
+		files = append(files, path)
+	}
+
+	return files, scanner.Err()
+}
+
+func (a *AptManager) Owner(ctx context.Context, path string) (string, error) {
+	// dpkg records paths as the package shipped them, which on merged-/usr
+	// systems may differ from the path on disk (/bin/ls vs /usr/bin/ls)
+	paths := []string{path}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil && resolved != path {
+		paths = append(paths, resolved)
+	}
+	var candidates []string
+	for _, p := range paths {
+		candidates = append(candidates, p)
+		if strings.HasPrefix(p, "/usr/") {
+			candidates = append(candidates, strings.TrimPrefix(p, "/usr"))
+		} else {
+			candidates = append(candidates, "/usr"+p)
+		}
+	}
+
+	for _, p := range candidates {
+		output, err := exec.CommandContext(ctx, "dpkg", "-S", p).Output()
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(strings.NewReader(string(output)))
+		for scanner.Scan() {
+			line := scanner.Text()
+			if strings.HasPrefix(line, "diversion by") {
+				continue
+			}
+			owners, _, ok := strings.Cut(line, ": ")
+			if !ok {
+				continue
+			}
+			// Directories can belong to several packages; report the first
+			owner, _, _ := strings.Cut(owners, ", ")
+			owner, _, _ = strings.Cut(owner, ":") // drop the :arch qualifier
+			return owner, nil
+		}
+	}
+
+	return "", fmt.Errorf("%s is not owned by any apt package", path)
+}
+
+// index parses apt's downloaded package lists once per run.
+func (a *AptManager) index() (*Index, error) {
+	a.indexOnce.Do(func() {
+		a.idx, a.indexErr = buildAptIndex()
+	})
+	return a.idx, a.indexErr
+}
+
+func buildAptIndex() (*Index, error) {
+	files := aptListFiles()
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no apt package lists found (run apt update)")
+	}
+
+	// The same package appears in several lists (release, updates,
+	// security); keep the highest version like apt's candidate would be.
+	byName := make(map[string]PackageInfo)
+	for _, path := range files {
+		data, err := readAptList(path)
+		if err != nil {
+			continue
+		}
+		stanzas, err := parseControl(bytes.NewReader(data), "Package", "Version", "Installed-Size", "Description", "Description-en")
+		if err != nil {
+			continue
+		}
+		for _, st := range stanzas {
+			name := st["Package"]
+			if name == "" {
+				continue
+			}
+			if prev, ok := byName[name]; ok && compareDebVersions(prev.Version, st["Version"]) >= 0 {
+				continue
+			}
+			summary, _ := st.description()
+			byName[name] = PackageInfo{
+				Name:             name,
+				Version:          st["Version"],
+				CandidateVersion: st["Version"],
+				Description:      summary,
+				InstalledSize:    st.installedSize(),
+			}
+		}
+	}
+
+	packages := make([]PackageInfo, 0, len(byName))
+	for _, pkg := range byName {
+		packages = append(packages, pkg)
+	}
+	return newIndex(packages), nil
+}
+
+// aptListFiles returns the Packages index files apt has downloaded.
+func aptListFiles() []string {
+	output, err := exec.Command("apt-get", "indextargets", "--format", "$(FILENAME)", "Created-By: Packages").Output()
+	if err == nil {
+		var files []string
+		for _, line := range strings.Split(string(output), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				files = append(files, line)
+			}
+		}
+		if len(files) > 0 {
+			return files
+		}
+	}
+
+	// Older apt without indextargets: fall back to the usual location
+	files, _ := filepath.Glob("/var/lib/apt/lists/*_Packages*")
+	return files
+}
+
+// readAptList returns the decompressed contents of a Packages file. apt may
+// store them lz4/xz/zstd compressed, which apt-helper knows how to read.
+func readAptList(path string) ([]byte, error) {
+	if _, err := os.Stat("/usr/lib/apt/apt-helper"); err == nil {
+		return exec.Command("/usr/lib/apt/apt-helper", "cat-file", path).Output()
+	}
+
+	if strings.HasSuffix(path, "_Packages") || strings.HasSuffix(path, ".gz") {
+		return readMaybeGzip(path)
+	}
+	return nil, fmt.Errorf("%s: unsupported compression", path)
+}
@@ -4,11 +4,25 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
+	"time"
 )
 
-type BrewManager struct{}
+type BrewManager struct {
+	indexOnce sync.Once
+	idx       *Index
+	indexErr  error
+
+	sizesMu  sync.Mutex
+	kegSizes map[string]int64 // keg dir -> bytes
+}
 
 func (b *BrewManager) Name() string {
 	return "brew"
@@ -20,6 +34,10 @@ func (b *BrewManager) IsAvailable() bool {
 }
 
 func (b *BrewManager) Search(ctx context.Context, query string) ([]PackageInfo, error) {
+	if idx, err := b.index(); err == nil && idx.Len() > 0 {
+		return idx.Search(query), nil
+	}
+
 	cmd := exec.CommandContext(ctx, "brew", "search", query)
 	output, err := cmd.Output()
 	if err != nil {
@@ -41,14 +59,16 @@ func (b *BrewManager) Search(ctx context.Context, query string) ([]PackageInfo,
 	return results, scanner.Err()
 }
 
-func (b *BrewManager) Command(ctx context.Context, action string, pkg string) *exec.Cmd {
+func (b *BrewManager) Command(ctx context.Context, action string, pkgs ...string) *exec.Cmd {
 	switch action {
-	case "install":
-		return exec.CommandContext(ctx, "brew", "install", pkg)
-	case "uninstall":
-		return exec.CommandContext(ctx, "brew", "uninstall", pkg)
+	case "cleanup":
+		return exec.CommandContext(ctx, "brew", "cleanup")
+	case "hold":
+		action = "pin"
+	case "unhold":
+		action = "unpin"
 	}
-	return exec.CommandContext(ctx, "brew", action, pkg)
+	return exec.CommandContext(ctx, "brew", append([]string{action}, pkgs...)...)
 }
 
 func (b *BrewManager) NeedsSudo() bool {
@@ -79,23 +99,46 @@ func (b *BrewManager) IsInstalled(ctx context.Context, pkg string) (bool, error)
 	return true, nil
 }
 
-type brewInfoJSON struct {
-	Name    string   `json:"name"`
-	Version string   `json:"versions"`
-	Desc    string   `json:"desc"`
-	Tap     string   `json:"tap"`
-	Full    string   `json:"full_name"`
-}
-
 type brewInfoVersions struct {
 	Stable string `json:"stable"`
 }
 
-type brewInfoEntry struct {
-	Name     string           `json:"name"`
-	FullName string           `json:"full_name"`
-	Desc     string           `json:"desc"`
-	Versions brewInfoVersions `json:"versions"`
+type brewInstalledKeg struct {
+	Version            string `json:"version"`
+	Time               int64  `json:"time"`
+	PouredFromBottle   bool   `json:"poured_from_bottle"`
+	InstalledOnRequest bool   `json:"installed_on_request"`
+}
+
+type brewFormulaEntry struct {
+	Name              string             `json:"name"`
+	FullName          string             `json:"full_name"`
+	Desc              string             `json:"desc"`
+	Homepage          string             `json:"homepage"`
+	License           string             `json:"license"`
+	Caveats           string             `json:"caveats"`
+	Dependencies      []string           `json:"dependencies"`
+	BuildDependencies []string           `json:"build_dependencies"`
+	Versions          brewInfoVersions   `json:"versions"`
+	Installed         []brewInstalledKeg `json:"installed"`
+	LinkedKeg         string             `json:"linked_keg"`
+}
+
+// Casks use a different schema: "name" is a list of display names, the
+// identifier is the token, and "installed" is a single version string.
+type brewCaskEntry struct {
+	Token         string  `json:"token"`
+	Desc          string  `json:"desc"`
+	Homepage      string  `json:"homepage"`
+	Caveats       string  `json:"caveats"`
+	Version       string  `json:"version"`
+	Installed     *string `json:"installed"`
+	InstalledTime *int64  `json:"installed_time"`
+}
+
+type brewInfoResult struct {
+	Formulae []brewFormulaEntry `json:"formulae"`
+	Casks    []brewCaskEntry    `json:"casks"`
 }
 
 func (b *BrewManager) GetInfo(ctx context.Context, pkg string) (PackageInfo, error) {
@@ -105,40 +148,159 @@ func (b *BrewManager) GetInfo(ctx context.Context, pkg string) (PackageInfo, err
 		return PackageInfo{}, err
 	}
 
-	var result struct {
-		Formulae []brewInfoEntry `json:"formulae"`
-		Casks    []brewInfoEntry `json:"casks"`
-	}
+	var result brewInfoResult
 	if err := json.Unmarshal(output, &result); err != nil {
 		return PackageInfo{}, err
 	}
 
 	if len(result.Formulae) > 0 {
-		f := result.Formulae[0]
-		installed, _ := b.IsInstalled(ctx, pkg)
-		return PackageInfo{
-			Name:        f.Name,
-			Version:     f.Versions.Stable,
-			Description: f.Desc,
-			Installed:   installed,
-		}, nil
+		return result.Formulae[0].packageInfo(), nil
 	}
 
 	if len(result.Casks) > 0 {
-		c := result.Casks[0]
-		return PackageInfo{
-			Name:        c.Name,
-			Version:     c.Versions.Stable,
-			Description: c.Desc,
-			Installed:   false,
-		}, nil
+		return result.Casks[0].packageInfo(), nil
 	}
 
 	return PackageInfo{Name: pkg}, nil
 }
 
+func (f brewFormulaEntry) packageInfo() PackageInfo {
+	info := PackageInfo{
+		Name:             f.Name,
+		Version:          f.Versions.Stable,
+		Description:      f.Desc,
+		Homepage:         f.Homepage,
+		License:          f.License,
+		Caveats:          strings.TrimSpace(f.Caveats),
+		Depends:          f.Dependencies,
+		BuildDepends:     f.BuildDependencies,
+		CandidateVersion: f.Versions.Stable,
+		Installed:        len(f.Installed) > 0,
+	}
+
+	for _, keg := range f.Installed {
+		info.InstalledVersions = append(info.InstalledVersions, keg.Version)
+		info.InstalledOnRequest = info.InstalledOnRequest || keg.InstalledOnRequest
+	}
+
+	if len(f.Installed) > 0 {
+		// The linked keg is the one on PATH; fall back to the newest install
+		current := f.Installed[len(f.Installed)-1]
+		for _, keg := range f.Installed {
+			if keg.Version == f.LinkedKeg {
+				current = keg
+			}
+		}
+		info.InstalledVersion = current.Version
+		info.Version = current.Version
+		info.PouredFromBottle = current.PouredFromBottle
+		if current.Time > 0 {
+			info.UpdateTime = time.Unix(current.Time, 0)
+		}
+		// The oldest keg still around is the best guess at the first install
+		for _, keg := range f.Installed {
+			if keg.Time > 0 && (info.InstallTime.IsZero() || time.Unix(keg.Time, 0).Before(info.InstallTime)) {
+				info.InstallTime = time.Unix(keg.Time, 0)
+			}
+		}
+	}
+
+	return info
+}
+
+func (c brewCaskEntry) packageInfo() PackageInfo {
+	info := PackageInfo{
+		Name:             c.Token,
+		Version:          c.Version,
+		Description:      c.Desc,
+		Homepage:         c.Homepage,
+		Caveats:          strings.TrimSpace(c.Caveats),
+		CandidateVersion: c.Version,
+	}
+	if c.Installed != nil {
+		info.Installed = true
+		info.InstalledVersion = *c.Installed
+		info.InstalledVersions = []string{*c.Installed}
+		info.Version = *c.Installed
+	}
+	if c.InstalledTime != nil {
+		info.InstallTime = time.Unix(*c.InstalledTime, 0)
+	}
+	return info
+}
+
 func (b *BrewManager) ListInstalled(ctx context.Context) ([]PackageInfo, error) {
-	cmd := exec.CommandContext(ctx, "brew", "list", "--formula", "-1")
+	// One JSON call gives names, versions and descriptions for everything
+	cmd := exec.CommandContext(ctx, "brew", "info", "--json=v2", "--installed")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var result brewInfoResult
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, err
+	}
+
+	cellar, _ := b.cellar(ctx)
+
+	results := make([]PackageInfo, 0, len(result.Formulae))
+	for _, f := range result.Formulae {
+		info := f.packageInfo()
+		if cellar != "" {
+			info.InstalledSize = b.formulaSize(filepath.Join(cellar, f.Name))
+		}
+		results = append(results, info)
+	}
+
+	return results, nil
+}
+
+// formulaSize sums the sizes of a formula's kegs, one dir per installed
+// version. A keg doesn't change once poured, so each is walked once and
+// its size kept for later lists.
+func (b *BrewManager) formulaSize(dir string) int64 {
+	kegs, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+	b.sizesMu.Lock()
+	defer b.sizesMu.Unlock()
+	if b.kegSizes == nil {
+		b.kegSizes = make(map[string]int64)
+	}
+	var total int64
+	for _, keg := range kegs {
+		path := filepath.Join(dir, keg.Name())
+		size, ok := b.kegSizes[path]
+		if !ok {
+			size = dirSize(path)
+			b.kegSizes[path] = size
+		}
+		total += size
+	}
+	return total
+}
+
+// dirSize sums the sizes of the regular files under dir.
+func dirSize(dir string) int64 {
+	var total int64
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if fi, err := d.Info(); err == nil {
+				total += fi.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
+
+func (b *BrewManager) ListManuallyInstalled(ctx context.Context) ([]PackageInfo, error) {
+	cmd := exec.CommandContext(ctx, "brew", "leaves")
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, err
@@ -160,25 +322,138 @@ func (b *BrewManager) ListInstalled(ctx context.Context) ([]PackageInfo, error)
 	return results, scanner.Err()
 }
 
-func (b *BrewManager) ListManuallyInstalled(ctx context.Context) ([]PackageInfo, error) {
-	cmd := exec.CommandContext(ctx, "brew", "leaves")
+func (b *BrewManager) Files(ctx context.Context, pkg string) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "brew", "list", pkg)
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, err
 	}
 
-	var results []PackageInfo
+	var files []string
 	scanner := bufio.NewScanner(strings.NewReader(string(output)))
 	for scanner.Scan() {
-		name := strings.TrimSpace(scanner.Text())
-		if name == "" {
+		path := strings.TrimSpace(scanner.Text())
+		if path == "" {
 			continue
 		}
-		results = append(results, PackageInfo{
-			Name:      name,
-			Installed: true,
-		})
+		files = append(files, path)
 	}
 
-	return results, scanner.Err()
+	return files, scanner.Err()
+}
+
+func (b *BrewManager) Owner(ctx context.Context, path string) (string, error) {
+	cellar, err := b.cellar(ctx)
+	if err != nil {
+		return "", err
+	}
+	// The Cellar itself may live behind a symlink (e.g. /usr/local on Intel)
+	if resolved, err := filepath.EvalSymlinks(cellar); err == nil {
+		cellar = resolved
+	}
+
+	// Everything brew links into the prefix (bin/, opt/, etc/...) is a
+	// symlink into Cellar/<formula>/<version>/
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(cellar, resolved)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("%s is not owned by any brew package", path)
+	}
+	formula, _, _ := strings.Cut(rel, string(filepath.Separator))
+	return formula, nil
+}
+
+// StatePaths returns the Cellar and Caskroom, whose entries change whenever
+// a formula or cask is installed, upgraded or removed.
+func (b *BrewManager) StatePaths() []string {
+	cellar, err := b.cellar(context.Background())
+	if err != nil {
+		return nil
+	}
+	return []string{cellar, filepath.Join(filepath.Dir(cellar), "Caskroom")}
+}
+
+// cellar locates the Cellar without starting brew when possible, since every
+// brew invocation pays for booting Ruby. It checks $HOMEBREW_CELLAR, then the
+// Cellar next to the brew binary, and only then asks brew --cellar.
+func (b *BrewManager) cellar(ctx context.Context) (string, error) {
+	if dir := os.Getenv("HOMEBREW_CELLAR"); dir != "" {
+		return dir, nil
+	}
+	if brew, err := exec.LookPath("brew"); err == nil {
+		dir := filepath.Join(filepath.Dir(filepath.Dir(brew)), "Cellar")
+		if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+			return dir, nil
+		}
+	}
+	output, err := exec.CommandContext(ctx, "brew", "--cellar").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// index parses the formula and cask catalogs brew caches from its JSON API
+// once per run.
+func (b *BrewManager) index() (*Index, error) {
+	b.indexOnce.Do(func() {
+		b.idx, b.indexErr = buildBrewIndex()
+	})
+	return b.idx, b.indexErr
+}
+
+func buildBrewIndex() (*Index, error) {
+	apiDir := filepath.Join(brewCacheDir(), "api")
+
+	var formulae []brewFormulaEntry
+	formulaErr := readBrewAPIFile(filepath.Join(apiDir, "formula.jws.json"), &formulae)
+	var casks []brewCaskEntry
+	caskErr := readBrewAPIFile(filepath.Join(apiDir, "cask.jws.json"), &casks)
+	if formulaErr != nil && caskErr != nil {
+		return nil, formulaErr
+	}
+
+	packages := make([]PackageInfo, 0, len(formulae)+len(casks))
+	for _, f := range formulae {
+		packages = append(packages, f.packageInfo())
+	}
+	for _, c := range casks {
+		packages = append(packages, c.packageInfo())
+	}
+	return newIndex(packages), nil
+}
+
+// readBrewAPIFile decodes one of brew's cached API files. They are JSON Web
+// Signatures whose payload is the JSON-encoded catalog as a string.
+func readBrewAPIFile(path string, v interface{}) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var jws struct {
+		Payload string `json:"payload"`
+	}
+	if err := json.Unmarshal(raw, &jws); err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(jws.Payload), v)
+}
+
+// brewCacheDir mirrors `brew --cache` without starting brew.
+func brewCacheDir() string {
+	if dir := os.Getenv("HOMEBREW_CACHE"); dir != "" {
+		return dir
+	}
+	home, _ := os.UserHomeDir()
+	if runtime.GOOS == "darwin" {
+		return filepath.Join(home, "Library", "Caches", "Homebrew")
+	}
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "Homebrew")
+	}
+	return filepath.Join(home, ".cache", "Homebrew")
 }
@@ -0,0 +1,207 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// stateTracker is implemented by managers that can name the files or
+// directories whose modification times change whenever the set of
+// installed packages changes. The cache uses them to detect staleness.
+type stateTracker interface {
+	StatePaths() []string
+}
+
+// cacheVersion is bumped whenever PackageInfo gains fields the lists fill
+// in, so caches written by older versions are rebuilt rather than shown
+// with those fields missing.
+const cacheVersion = 2
+
+// cacheData is the on-disk cache format. Each section records the stamp of
+// the package database it was read at, so sections refreshed at different
+// times are invalidated independently.
+type cacheData struct {
+	Version   int                    `json:"version"`
+	Installed cachedList             `json:"installed"`
+	Manual    cachedList             `json:"manual"`
+	InfoStamp string                 `json:"info_stamp"`
+	Info      map[string]PackageInfo `json:"info"`
+}
+
+type cachedList struct {
+	Stamp    string        `json:"stamp"`
+	Packages []PackageInfo `json:"packages"`
+}
+
+// CachedManager wraps a PackageManager and persists the installed and
+// manually installed lists and info lookups under the user cache dir.
+// Entries are reused until the manager's state paths change on disk.
+type CachedManager struct {
+	PackageManager
+
+	path   string
+	mu     sync.Mutex
+	data   *cacheData
+	loaded bool
+}
+
+// WithCache wraps mgr with a disk cache. If the cache dir can't be
+// determined the cache only lives in memory.
+func WithCache(mgr PackageManager) *CachedManager {
+	c := &CachedManager{PackageManager: mgr}
+	if dir, err := os.UserCacheDir(); err == nil {
+		c.path = filepath.Join(dir, "boxy", mgr.Name()+".json")
+	}
+	return c
+}
+
+// Cached returns the lists from the last run without checking whether they
+// are still current, so the UI can render immediately while it refreshes.
+func (c *CachedManager) Cached() (installed, manual []PackageInfo, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := c.load()
+	if data.Installed.Packages == nil {
+		return nil, nil, false
+	}
+	return data.Installed.Packages, data.Manual.Packages, true
+}
+
+func (c *CachedManager) ListInstalled(ctx context.Context) ([]PackageInfo, error) {
+	return c.cachedList(ctx, func(d *cacheData) *cachedList { return &d.Installed }, c.PackageManager.ListInstalled)
+}
+
+func (c *CachedManager) ListManuallyInstalled(ctx context.Context) ([]PackageInfo, error) {
+	return c.cachedList(ctx, func(d *cacheData) *cachedList { return &d.Manual }, c.PackageManager.ListManuallyInstalled)
+}
+
+func (c *CachedManager) cachedList(ctx context.Context, section func(*cacheData) *cachedList, fetch func(context.Context) ([]PackageInfo, error)) ([]PackageInfo, error) {
+	stamp := c.stamp()
+
+	c.mu.Lock()
+	list := section(c.load())
+	if stamp != "" && list.Stamp == stamp {
+		c.mu.Unlock()
+		return list.Packages, nil
+	}
+	c.mu.Unlock()
+
+	packages, err := fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	*section(c.load()) = cachedList{Stamp: stamp, Packages: packages}
+	c.save()
+	return packages, nil
+}
+
+func (c *CachedManager) GetInfo(ctx context.Context, pkg string) (PackageInfo, error) {
+	stamp := c.stamp()
+
+	c.mu.Lock()
+	data := c.load()
+	if stamp != "" && data.InfoStamp == stamp {
+		if info, ok := data.Info[pkg]; ok {
+			c.mu.Unlock()
+			return info, nil
+		}
+	}
+	c.mu.Unlock()
+
+	info, err := c.PackageManager.GetInfo(ctx, pkg)
+	if err != nil {
+		return PackageInfo{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data = c.load()
+	if data.InfoStamp != stamp || data.Info == nil {
+		data.InfoStamp = stamp
+		data.Info = make(map[string]PackageInfo)
+	}
+	data.Info[pkg] = info
+	c.save()
+	return info, nil
+}
+
+// stamp summarizes the modification times of the manager's state paths.
+// Directories include their immediate entries, since brew upgrades add a
+// version directory inside Cellar/<formula> without touching the Cellar.
+// An empty stamp means staleness can't be detected and nothing is fresh.
+func (c *CachedManager) stamp() string {
+	tracker, ok := c.PackageManager.(stateTracker)
+	if !ok {
+		return ""
+	}
+
+	var parts []string
+	for _, path := range tracker.StatePaths() {
+		fi, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		latest := fi.ModTime()
+		if fi.IsDir() {
+			entries, _ := os.ReadDir(path)
+			for _, entry := range entries {
+				if info, err := entry.Info(); err == nil && info.ModTime().After(latest) {
+					latest = info.ModTime()
+				}
+			}
+		}
+		parts = append(parts, fmt.Sprintf("%s=%d", path, latest.UnixNano()))
+	}
+	return strings.Join(parts, ";")
+}
+
+// load reads the cache file on first use. Callers must hold c.mu.
+func (c *CachedManager) load() *cacheData {
+	if c.loaded {
+		return c.data
+	}
+	c.loaded = true
+	c.data = &cacheData{Version: cacheVersion}
+
+	if c.path == "" {
+		return c.data
+	}
+	raw, err := os.ReadFile(c.path)
+	if err != nil {
+		return c.data
+	}
+	var data cacheData
+	if err := json.Unmarshal(raw, &data); err == nil && data.Version == cacheVersion {
+		c.data = &data
+	}
+	return c.data
+}
+
+// save writes the cache atomically. Errors are ignored: a missing cache only
+// costs a slower start. Callers must hold c.mu.
+func (c *CachedManager) save() {
+	if c.path == "" {
+		return
+	}
+	raw, err := json.Marshal(c.data)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0644); err != nil {
+		return
+	}
+	os.Rename(tmp, c.path)
+}
@@ -0,0 +1,278 @@
+package manager
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// CargoManager manages crates installed with `cargo install`, whose
+// commands go in $CARGO_INSTALL_ROOT/bin or $CARGO_HOME/bin.
+type CargoManager struct {
+	userTool
+
+	indexOnce sync.Once
+	indexed   map[string]string // crate name -> its file in the index cache
+}
+
+// cargoSearchLimit caps the crates a search returns, as each one's
+// versions are read from its file
+const cargoSearchLimit = 100
+
+// NewCargoManager returns the manager for crates installed with cargo
+func NewCargoManager() *CargoManager {
+	c := &CargoManager{}
+	c.self = c
+	return c
+}
+
+// crate is one installed crate and the commands it put on PATH
+type crate struct {
+	name    string
+	version string
+	bins    []string
+}
+
+func (c *CargoManager) Name() string {
+	return "cargo"
+}
+
+func (c *CargoManager) IsAvailable() bool {
+	_, err := exec.LookPath("cargo")
+	return err == nil
+}
+
+// cargoHome is $CARGO_HOME, defaulting to ~/.cargo
+func cargoHome() string {
+	if home := os.Getenv("CARGO_HOME"); home != "" {
+		return home
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".cargo")
+}
+
+// installRoot is where cargo install puts its bin dir and records what it
+// installed
+func installRoot() string {
+	if root := os.Getenv("CARGO_INSTALL_ROOT"); root != "" {
+		return root
+	}
+	return cargoHome()
+}
+
+// StatePaths returns the files cargo rewrites on every install and
+// uninstall
+func (c *CargoManager) StatePaths() []string {
+	root := installRoot()
+	return []string{filepath.Join(root, ".crates.toml"), filepath.Join(root, ".crates2.json")}
+}
+
+func (c *CargoManager) list(ctx context.Context) ([]crate, error) {
+	output, err := exec.CommandContext(ctx, "cargo", "install", "--list").Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseCargoList(output, filepath.Join(installRoot(), "bin"))
+}
+
+// parseCargoList reads `cargo install --list`: each crate as "name
+// vVERSION:" or "name vVERSION (source):", followed by its commands
+// indented, which are in bin
+func parseCargoList(output []byte, bin string) ([]crate, error) {
+	var crates []crate
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, " ") {
+			if len(crates) > 0 {
+				last := &crates[len(crates)-1]
+				last.bins = append(last.bins, filepath.Join(bin, strings.TrimSpace(line)))
+			}
+			continue
+		}
+		fields := strings.Fields(strings.TrimSuffix(line, ":"))
+		if len(fields) < 2 || !strings.HasPrefix(fields[1], "v") {
+			continue
+		}
+		crates = append(crates, crate{name: fields[0], version: strings.TrimPrefix(fields[1], "v")})
+	}
+	return crates, scanner.Err()
+}
+
+func (c *CargoManager) find(ctx context.Context, pkg string) (crate, bool, error) {
+	crates, err := c.list(ctx)
+	if err != nil {
+		return crate{}, false, err
+	}
+	for _, cr := range crates {
+		if cr.name == pkg {
+			return cr, true, nil
+		}
+	}
+	return crate{}, false, nil
+}
+
+func (c *CargoManager) ListInstalled(ctx context.Context) ([]PackageInfo, error) {
+	crates, err := c.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]PackageInfo, len(crates))
+	for i, cr := range crates {
+		infos[i] = PackageInfo{Name: cr.name, Version: cr.version, InstalledVersion: cr.version, Installed: true}
+	}
+	return infos, nil
+}
+
+func (c *CargoManager) IsInstalled(ctx context.Context, pkg string) (bool, error) {
+	_, ok, err := c.find(ctx, pkg)
+	return ok, err
+}
+
+// Search matches crate names in cargo's local copy of the registry index,
+// which holds the crates this machine has fetched for any build. It
+// doesn't ask crates.io, whose search is only available over its web API.
+// Only the best cargoSearchLimit matches get their versions read.
+func (c *CargoManager) Search(ctx context.Context, query string) ([]PackageInfo, error) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil, nil
+	}
+	indexed := c.indexedCrates()
+	var results []PackageInfo
+	for name := range indexed {
+		if strings.Contains(name, query) {
+			results = append(results, PackageInfo{Name: name})
+		}
+	}
+	results = Rank(results, query)
+	if len(results) > cargoSearchLimit {
+		results = results[:cargoSearchLimit]
+	}
+	for i := range results {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		results[i].Version = latestCachedVersion(indexed[results[i].Name])
+	}
+	return results, nil
+}
+
+// indexedCrates walks the registry index cache once, for the file of each
+// crate in it, named after the crate. Crates cargo fetches later in the
+// session aren't seen.
+func (c *CargoManager) indexedCrates() map[string]string {
+	c.indexOnce.Do(func() {
+		c.indexed = make(map[string]string)
+		caches, _ := filepath.Glob(filepath.Join(cargoHome(), "registry", "index", "*", ".cache"))
+		for _, dir := range caches {
+			filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+				if err == nil && !d.IsDir() {
+					if _, ok := c.indexed[d.Name()]; !ok {
+						c.indexed[d.Name()] = path
+					}
+				}
+				return nil
+			})
+		}
+	})
+	return c.indexed
+}
+
+// latestCachedVersion reads the newest release from a crate's file in the
+// index cache: a header followed by NUL-separated pairs of version and
+// the version's JSON entry. Yanked versions and pre-releases are skipped.
+func latestCachedVersion(path string) string {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	var latest string
+	for _, field := range bytes.Split(raw, []byte{0}) {
+		if len(field) == 0 || field[0] != '{' {
+			continue
+		}
+		var entry struct {
+			Vers   string `json:"vers"`
+			Yanked bool   `json:"yanked"`
+		}
+		if json.Unmarshal(field, &entry) != nil || entry.Yanked || strings.Contains(entry.Vers, "-") {
+			continue
+		}
+		if latest == "" || compareDebVersions(entry.Vers, latest) > 0 {
+			latest = entry.Vers
+		}
+	}
+	return latest
+}
+
+// GetInfo describes an installed crate from cargo's own records; cargo
+// has no offline way to describe others
+func (c *CargoManager) GetInfo(ctx context.Context, pkg string) (PackageInfo, error) {
+	cr, ok, err := c.find(ctx, pkg)
+	if err != nil {
+		return PackageInfo{}, err
+	}
+	if !ok {
+		return PackageInfo{}, fmt.Errorf("cargo: no information about %s, which isn't installed", pkg)
+	}
+	return PackageInfo{
+		Name:             cr.name,
+		Version:          cr.version,
+		Installed:        true,
+		InstalledVersion: cr.version,
+		Homepage:         "https://crates.io/crates/" + cr.name,
+	}, nil
+}
+
+// Files lists the commands the crate put on PATH
+func (c *CargoManager) Files(ctx context.Context, pkg string) ([]string, error) {
+	cr, ok, err := c.find(ctx, pkg)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s is not installed with cargo", pkg)
+	}
+	return cr.bins, nil
+}
+
+// Owner finds the crate that put path on PATH
+func (c *CargoManager) Owner(ctx context.Context, path string) (string, error) {
+	crates, err := c.list(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, cr := range crates {
+		for _, bin := range cr.bins {
+			if sameFile(bin, path) {
+				return cr.name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no cargo crate owns %s", path)
+}
+
+// Command builds cargo's command for an action. Installing an installed
+// crate upgrades it if there's a newer version.
+func (c *CargoManager) Command(ctx context.Context, action string, pkgs ...string) *exec.Cmd {
+	switch action {
+	case "install", "upgrade":
+		return exec.CommandContext(ctx, "cargo", append([]string{"install"}, pkgs...)...)
+	case "uninstall":
+		return exec.CommandContext(ctx, "cargo", append([]string{"uninstall"}, pkgs...)...)
+	}
+	return unsupported(ctx, "cargo", action)
+}
+
+func (c *CargoManager) pinned(name, version string) string {
+	return name + "@" + version
+}
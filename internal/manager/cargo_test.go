@@ -0,0 +1,98 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// cargoInstallList is `cargo install --list` with crates from crates.io, a
+// git repository and a local path
+const cargoInstallList = `cargo-edit v0.12.2:
+    cargo-add
+    cargo-rm
+    cargo-set-version
+    cargo-upgrade
+ripgrep v14.1.0:
+    rg
+starship v1.18.0 (https://github.com/starship/starship#4a2bd2ec):
+    starship
+mytool v0.1.0 (/home/alice/src/mytool):
+    mytool
+`
+
+func TestParseCargoList(t *testing.T) {
+	bin := "/home/alice/.cargo/bin"
+	tests := []struct {
+		name   string
+		output string
+		want   []crate
+	}{
+		{
+			name:   "crates and their commands",
+			output: cargoInstallList,
+			want: []crate{
+				{name: "cargo-edit", version: "0.12.2", bins: []string{bin + "/cargo-add", bin + "/cargo-rm", bin + "/cargo-set-version", bin + "/cargo-upgrade"}},
+				{name: "ripgrep", version: "14.1.0", bins: []string{bin + "/rg"}},
+				{name: "starship", version: "1.18.0", bins: []string{bin + "/starship"}},
+				{name: "mytool", version: "0.1.0", bins: []string{bin + "/mytool"}},
+			},
+		},
+		{
+			name:   "nothing installed",
+			output: "",
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCargoList([]byte(tt.output), bin)
+			if err != nil {
+				t.Fatalf("parseCargoList: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCargoList = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLatestCachedVersion(t *testing.T) {
+	// A crate's file in the registry index cache: a version byte, the
+	// index version, then NUL-separated version and JSON entry pairs
+	entry := func(vers string, yanked bool) string {
+		y := "false"
+		if yanked {
+			y = "true"
+		}
+		return vers + "\x00" + `{"name":"ripgrep","vers":"` + vers + `","deps":[],"cksum":"0","features":{},"yanked":` + y + `}` + "\x00"
+	}
+	header := "\x03\x02\x00\x00\x00" + "etag: W/\"1a2b\"\x00"
+	tests := []struct {
+		name string
+		file string
+		want string
+	}{
+		{"newest release", header + entry("13.0.0", false) + entry("14.1.0", false) + entry("14.0.3", false), "14.1.0"},
+		{"numeric rather than string order", header + entry("9.9.9", false) + entry("10.0.0", false), "10.0.0"},
+		{"skips yanked versions", header + entry("14.0.0", false) + entry("14.0.1", true), "14.0.0"},
+		{"skips pre-releases", header + entry("14.0.0", false) + entry("15.0.0-rc.1", false), "14.0.0"},
+		{"nothing released", header + entry("0.1.0", true), ""},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "ripgrep")
+			if err := os.WriteFile(path, []byte(tt.file), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if got := latestCachedVersion(path); got != tt.want {
+				t.Errorf("latestCachedVersion = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if got := latestCachedVersion(filepath.Join(dir, "missing")); got != "" {
+		t.Errorf("latestCachedVersion of a missing file = %q, want none", got)
+	}
+}
@@ -0,0 +1,136 @@
+package manager
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// controlStanza is a single RFC822-style paragraph as printed by
+// apt-cache show, dpkg-query -s, or stored in apt's Packages lists.
+type controlStanza map[string]string
+
+// parseControl splits Debian control data into stanzas. Continuation lines
+// (starting with a space or tab) are appended to the previous field with
+// their newline preserved, so multi-line fields like Description survive.
+// If fields are given, only those are kept, which keeps parsing the full
+// package lists cheap.
+func parseControl(r io.Reader, fields ...string) ([]controlStanza, error) {
+	var keep map[string]bool
+	if len(fields) > 0 {
+		keep = make(map[string]bool, len(fields))
+		for _, f := range fields {
+			keep[f] = true
+		}
+	}
+
+	var stanzas []controlStanza
+	current := controlStanza{}
+	lastField := ""
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			if len(current) > 0 {
+				stanzas = append(stanzas, current)
+				current = controlStanza{}
+			}
+			lastField = ""
+			continue
+		}
+
+		if line[0] == ' ' || line[0] == '\t' {
+			if lastField != "" {
+				current[lastField] += "\n" + line
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || (keep != nil && !keep[key]) {
+			lastField = ""
+			continue
+		}
+		lastField = key
+		current[key] = strings.TrimSpace(value)
+	}
+	if len(current) > 0 {
+		stanzas = append(stanzas, current)
+	}
+
+	return stanzas, scanner.Err()
+}
+
+// description returns the one-line synopsis and the extended description
+// of a stanza. Translated descriptions (Description-en) are used when the
+// plain field is missing.
+func (s controlStanza) description() (string, string) {
+	desc, ok := s["Description"]
+	if !ok {
+		desc = s["Description-en"]
+	}
+	summary, long, _ := strings.Cut(desc, "\n")
+	return summary, formatLongDescription(long)
+}
+
+// formatLongDescription turns the extended description into plain text.
+// Per Debian policy, lines with a single leading space are word-wrapped
+// paragraph text (so they are joined here and left to the UI to wrap),
+// lines with more indentation are shown verbatim, and " ." is a blank line.
+func formatLongDescription(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	var lines []string
+	joinable := false
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimPrefix(line, " ")
+		switch {
+		case line == ".":
+			lines = append(lines, "")
+			joinable = false
+		case strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t"):
+			lines = append(lines, line)
+			joinable = false
+		case joinable:
+			lines[len(lines)-1] += " " + line
+		default:
+			lines = append(lines, line)
+			joinable = true
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// list splits a comma-separated relationship field such as Depends into
+// its entries, folding continuation lines.
+func (s controlStanza) list(field string) []string {
+	raw := strings.ReplaceAll(s[field], "\n", " ")
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	var out []string
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.Join(strings.Fields(part), " ")
+		if part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// installedSize returns Installed-Size in bytes (the field is in KiB).
+func (s controlStanza) installedSize() int64 {
+	return kibToBytes(s["Installed-Size"])
+}
+
+func kibToBytes(kib string) int64 {
+	n, err := strconv.ParseInt(strings.TrimSpace(kib), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n * 1024
+}
@@ -0,0 +1,118 @@
+package manager
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// aptCacheShow is `apt-cache show` output for two versions of a package,
+// trimmed to a few fields
+const aptCacheShow = `Package: curl
+Version: 7.88.1-10+deb12u5
+Installed-Size: 500
+Maintainer: Alessandro Ghedini <ghedo@debian.org>
+Architecture: amd64
+Depends: libc6 (>= 2.34), libcurl4 (= 7.88.1-10+deb12u5),
+ zlib1g (>= 1:1.1.4)
+Description: command line tool for transferring data with URL syntax
+ curl is a command line tool for transferring data with URL syntax,
+ supporting DICT, FILE, FTP, FTPS, GOPHER, HTTP, HTTPS.
+ .
+ Supported protocols:
+   - HTTP
+   - FTP
+Homepage: https://curl.se/
+
+Package: curl
+Version: 7.74.0-1.3+deb11u7
+Installed-Size: 412
+Description-en: command line tool for transferring data with URL syntax
+
+`
+
+func TestParseControl(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		fields []string
+		want   []controlStanza
+	}{
+		{
+			name:   "stanzas and continuation lines",
+			input:  aptCacheShow,
+			fields: []string{"Package", "Version", "Depends"},
+			want: []controlStanza{
+				{"Package": "curl", "Version": "7.88.1-10+deb12u5", "Depends": "libc6 (>= 2.34), libcurl4 (= 7.88.1-10+deb12u5),\n zlib1g (>= 1:1.1.4)"},
+				{"Package": "curl", "Version": "7.74.0-1.3+deb11u7"},
+			},
+		},
+		{
+			name:   "continuation of a dropped field is dropped",
+			input:  "Package: jq\nDescription: JSON processor\n jq is like sed for JSON\nVersion: 1.6-2.1\n",
+			fields: []string{"Package", "Version"},
+			want:   []controlStanza{{"Package": "jq", "Version": "1.6-2.1"}},
+		},
+		{
+			name:  "all fields without a trailing blank line",
+			input: "Package: jq\nStatus: install ok installed\n",
+			want:  []controlStanza{{"Package": "jq", "Status": "install ok installed"}},
+		},
+		{
+			name:  "runs of blank lines",
+			input: "\n\nPackage: a\n\n  \n\nPackage: b\n",
+			want:  []controlStanza{{"Package": "a"}, {"Package": "b"}},
+		},
+		{
+			name:  "empty",
+			input: "",
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseControl(strings.NewReader(tt.input), tt.fields...)
+			if err != nil {
+				t.Fatalf("parseControl: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseControl = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestControlStanzaFields(t *testing.T) {
+	stanzas, err := parseControl(strings.NewReader(aptCacheShow))
+	if err != nil {
+		t.Fatalf("parseControl: %v", err)
+	}
+	current, old := stanzas[0], stanzas[1]
+
+	summary, long := current.description()
+	if want := "command line tool for transferring data with URL syntax"; summary != want {
+		t.Errorf("summary = %q, want %q", summary, want)
+	}
+	wantLong := "curl is a command line tool for transferring data with URL syntax, supporting DICT, FILE, FTP, FTPS, GOPHER, HTTP, HTTPS.\n" +
+		"\n" +
+		"Supported protocols:\n" +
+		"  - HTTP\n" +
+		"  - FTP"
+	if long != wantLong {
+		t.Errorf("long description = %q, want %q", long, wantLong)
+	}
+	if summary, long := old.description(); summary == "" || long != "" {
+		t.Errorf("Description-en = %q, %q, want the synopsis only", summary, long)
+	}
+
+	wantDepends := []string{"libc6 (>= 2.34)", "libcurl4 (= 7.88.1-10+deb12u5)", "zlib1g (>= 1:1.1.4)"}
+	if got := current.list("Depends"); !reflect.DeepEqual(got, wantDepends) {
+		t.Errorf("Depends = %q, want %q", got, wantDepends)
+	}
+	if got := old.list("Depends"); got != nil {
+		t.Errorf("missing Depends = %q, want nil", got)
+	}
+	if got := current.installedSize(); got != 500*1024 {
+		t.Errorf("installedSize = %d, want %d", got, 500*1024)
+	}
+}
@@ -2,18 +2,37 @@ package manager
 
 import "runtime"
 
+// Detect returns the platform's preferred package manager, or nil if none
+// is available.
 func Detect() PackageManager {
-	switch runtime.GOOS {
-	case "darwin":
-		mgr := &BrewManager{}
-		if mgr.IsAvailable() {
-			return mgr
+	if all := DetectAll(); len(all) > 0 {
+		return all[0]
+	}
+	return nil
+}
+
+// DetectAll returns every available package manager, the platform's
+// preferred one first (Homebrew also runs on Linux), followed by the
+// user-level installers (pipx, uv, npm -g, cargo install, go install) and
+// plugins found on PATH. A plugin can't take a built-in manager's name.
+func DetectAll() []PackageManager {
+	candidates := []PackageManager{&AptManager{}, &BrewManager{}}
+	if runtime.GOOS == "darwin" {
+		candidates = []PackageManager{&BrewManager{}}
+	}
+	candidates = append(candidates, NewPipxManager(), NewUvManager(), NewNpmManager(), NewCargoManager(), NewGoManager())
+	builtin := map[string]bool{"apt": true, "brew": true, "pipx": true, "uv": true, "npm": true, "cargo": true, "go": true}
+	for _, plugin := range FindPlugins() {
+		if !builtin[plugin.Name()] {
+			candidates = append(candidates, plugin)
 		}
-	case "linux":
-		mgr := &AptManager{}
+	}
+
+	var available []PackageManager
+	for _, mgr := range candidates {
 		if mgr.IsAvailable() {
-			return mgr
+			available = append(available, mgr)
 		}
 	}
-	return nil
+	return available
 }
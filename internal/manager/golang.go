@@ -0,0 +1,281 @@
+package manager
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// GoManager manages the commands installed with `go install`. Go keeps no
+// record of them, so the installed list is whatever Go binaries are in
+// $GOBIN (or $GOPATH/bin), each named by the import path it was built from
+// as `go version -m` reports it. There's no registry to search.
+type GoManager struct {
+	userTool
+
+	binOnce sync.Once
+	bin     string
+	binErr  error
+}
+
+// goBinary is one command in the bin dir and what it was built from
+type goBinary struct {
+	path    string // the binary
+	pkg     string // main package import path
+	module  string
+	version string
+	deps    []string
+}
+
+// NewGoManager returns the manager for commands installed with go install
+func NewGoManager() *GoManager {
+	g := &GoManager{}
+	g.self = g
+	return g
+}
+
+func (g *GoManager) Name() string {
+	return "go"
+}
+
+func (g *GoManager) IsAvailable() bool {
+	_, err := exec.LookPath("go")
+	return err == nil
+}
+
+func (g *GoManager) canSearch() bool {
+	return false
+}
+
+// binDir is where go install puts commands: $GOBIN, or the bin dir of the
+// first GOPATH entry
+func (g *GoManager) binDir() (string, error) {
+	g.binOnce.Do(func() {
+		output, err := exec.Command("go", "env", "GOBIN", "GOPATH").Output()
+		if err != nil {
+			g.binErr = fmt.Errorf("go env: %w", err)
+			return
+		}
+		g.bin = goBinDir(string(output))
+		if g.bin == "" {
+			g.binErr = fmt.Errorf("go env: neither GOBIN nor GOPATH is set")
+		}
+	})
+	return g.bin, g.binErr
+}
+
+// goBinDir reads `go env GOBIN GOPATH`, which prints one line per
+// variable, with GOBIN's empty when it isn't set
+func goBinDir(env string) string {
+	lines := strings.Split(env, "\n")
+	if gobin := strings.TrimSpace(lines[0]); gobin != "" {
+		return gobin
+	}
+	if len(lines) > 1 {
+		if gopath := filepath.SplitList(strings.TrimSpace(lines[1])); len(gopath) > 0 && gopath[0] != "" {
+			return filepath.Join(gopath[0], "bin")
+		}
+	}
+	return ""
+}
+
+// StatePaths returns the bin dir, which gains or loses an entry whenever a
+// command is installed or removed
+func (g *GoManager) StatePaths() []string {
+	dir, err := g.binDir()
+	if err != nil {
+		return nil
+	}
+	return []string{dir}
+}
+
+// list reads `go version -m` over the bin dir
+func (g *GoManager) list(ctx context.Context) ([]goBinary, error) {
+	dir, err := g.binDir()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil, nil
+	}
+	output, err := exec.CommandContext(ctx, "go", "version", "-m", dir).Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseGoVersion(output)
+}
+
+// parseGoVersion reads `go version -m`, which prints each Go binary as
+// "path: go1.x" followed by tab-indented lines of build info. Anything that
+// isn't a Go binary is skipped.
+func parseGoVersion(output []byte) ([]goBinary, error) {
+	var binaries []goBinary
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "\t") {
+			if path, _, ok := strings.Cut(line, ": "); ok {
+				binaries = append(binaries, goBinary{path: path})
+			}
+			continue
+		}
+		if len(binaries) == 0 {
+			continue
+		}
+		b := &binaries[len(binaries)-1]
+		fields := strings.Split(strings.TrimPrefix(line, "\t"), "\t")
+		switch {
+		case fields[0] == "path" && len(fields) > 1:
+			b.pkg = fields[1]
+		case fields[0] == "mod" && len(fields) > 2:
+			b.module, b.version = fields[1], fields[2]
+		case fields[0] == "dep" && len(fields) > 2:
+			b.deps = append(b.deps, fields[1]+" "+fields[2])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Binaries built without module info can't be reinstalled by path
+	named := binaries[:0]
+	for _, b := range binaries {
+		if b.pkg != "" {
+			named = append(named, b)
+		}
+	}
+	return named, nil
+}
+
+func (g *GoManager) find(ctx context.Context, pkg string) ([]goBinary, error) {
+	binaries, err := g.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var found []goBinary
+	for _, b := range binaries {
+		if b.pkg == pkg {
+			found = append(found, b)
+		}
+	}
+	return found, nil
+}
+
+func (g *GoManager) ListInstalled(ctx context.Context) ([]PackageInfo, error) {
+	binaries, err := g.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var infos []PackageInfo
+	for _, b := range binaries {
+		if seen[b.pkg] {
+			continue
+		}
+		seen[b.pkg] = true
+		infos = append(infos, PackageInfo{Name: b.pkg, Version: b.version, InstalledVersion: b.version, Installed: true})
+	}
+	return infos, nil
+}
+
+func (g *GoManager) IsInstalled(ctx context.Context, pkg string) (bool, error) {
+	found, err := g.find(ctx, pkg)
+	return len(found) > 0, err
+}
+
+// Search always fails: Go has no package registry to search. CanSearch
+// reports false, so boxy doesn't ask.
+func (g *GoManager) Search(ctx context.Context, query string) ([]PackageInfo, error) {
+	return nil, fmt.Errorf("go can't search for packages")
+}
+
+// GetInfo describes an installed command from its build info
+func (g *GoManager) GetInfo(ctx context.Context, pkg string) (PackageInfo, error) {
+	found, err := g.find(ctx, pkg)
+	if err != nil {
+		return PackageInfo{}, err
+	}
+	if len(found) == 0 {
+		return PackageInfo{}, fmt.Errorf("go: no information about %s, which isn't installed", pkg)
+	}
+	b := found[0]
+	return PackageInfo{
+		Name:             b.pkg,
+		Version:          b.version,
+		Installed:        true,
+		InstalledVersion: b.version,
+		Source:           b.module,
+		Homepage:         "https://pkg.go.dev/" + b.pkg,
+		Depends:          b.deps,
+	}, nil
+}
+
+// Files lists the binaries built from pkg
+func (g *GoManager) Files(ctx context.Context, pkg string) ([]string, error) {
+	found, err := g.find(ctx, pkg)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("%s is not installed with go", pkg)
+	}
+	files := make([]string, len(found))
+	for i, b := range found {
+		files[i] = b.path
+	}
+	return files, nil
+}
+
+// Owner finds the import path the binary at path was built from
+func (g *GoManager) Owner(ctx context.Context, path string) (string, error) {
+	binaries, err := g.list(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, b := range binaries {
+		if sameFile(b.path, path) {
+			return b.pkg, nil
+		}
+	}
+	return "", fmt.Errorf("no go install command owns %s", path)
+}
+
+// Command builds the command for an action. go install takes one module
+// version per run, so installs loop over the packages, at @latest unless
+// pinned; uninstalling deletes the binaries, as Go has no uninstall.
+func (g *GoManager) Command(ctx context.Context, action string, pkgs ...string) *exec.Cmd {
+	switch action {
+	case "install", "upgrade":
+		versioned := make([]string, len(pkgs))
+		for i, pkg := range pkgs {
+			versioned[i] = pkg
+			if !strings.Contains(pkg, "@") {
+				versioned[i] += "@latest"
+			}
+		}
+		return perPackage(ctx, []string{"go", "install"}, versioned)
+	case "uninstall":
+		var files []string
+		for _, pkg := range pkgs {
+			found, err := g.Files(ctx, pkg)
+			if err != nil {
+				cmd := unsupported(ctx, "go", action)
+				cmd.Err = err
+				return cmd
+			}
+			files = append(files, found...)
+		}
+		return exec.CommandContext(ctx, "rm", append([]string{"-f"}, files...)...)
+	}
+	return unsupported(ctx, "go", action)
+}
+
+func (g *GoManager) pinned(name, version string) string {
+	return name + "@" + version
+}
@@ -0,0 +1,103 @@
+package manager
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGoBinDir(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string // `go env GOBIN GOPATH`
+		want string
+	}{
+		{"GOBIN set", "/home/alice/bin\n/home/alice/go\n", "/home/alice/bin"},
+		{"GOBIN unset", "\n/home/alice/go\n", "/home/alice/go/bin"},
+		{"several GOPATH entries", "\n/home/alice/go:/opt/go\n", "/home/alice/go/bin"},
+		{"neither set", "\n\n", ""},
+		{"no output", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := goBinDir(tt.env); got != tt.want {
+				t.Errorf("goBinDir(%q) = %q, want %q", tt.env, got, tt.want)
+			}
+		})
+	}
+}
+
+// goVersionM is `go version -m` over a bin dir holding two commands from
+// one module, one without module info, and a file that isn't a Go binary,
+// which go reports on stderr
+const goVersionM = `/home/alice/go/bin/gopls: go1.22.1
+	path	golang.org/x/tools/gopls
+	mod	golang.org/x/tools/gopls	v0.15.2	h1:4JKt4inO8JaFW3l/Fh9X1k/5JQn+iUOpdc4/Lpi0mOs=
+	dep	github.com/BurntSushi/toml	v1.2.1	h1:9F2/+DoOYIOksmaJFPw1tGFy1eDnIJXg+UHjuD8lTak=
+	dep	golang.org/x/mod	v0.15.0	h1:SernR4v+D55NyBH2QiEQrlBAnj1ECL6AGrA5+dPaMY8=
+	build	-buildmode=exe
+	build	GOOS=linux
+/home/alice/go/bin/staticcheck: go1.22.1
+	path	honnef.co/go/tools/cmd/staticcheck
+	mod	honnef.co/go/tools	v0.4.7	h1:9MDAWxMoSnB6QoSqiVr7P5mtkT9pOc1kSxchzPCnxJs=
+	dep	golang.org/x/tools	v0.12.1-0.20230815132531-74c255bcf846	h1:Vve/L0v7CXXuxUmaMGIEK/dEeq7uiqb5qBgQrZzIE7E=
+/home/alice/go/bin/structlayout: go1.22.1
+	path	honnef.co/go/tools/cmd/structlayout
+	mod	honnef.co/go/tools	v0.4.7	h1:9MDAWxMoSnB6QoSqiVr7P5mtkT9pOc1kSxchzPCnxJs=
+/home/alice/go/bin/hello: go1.22.1
+`
+
+func TestParseGoVersion(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []goBinary
+	}{
+		{
+			name:   "binaries with build info",
+			output: goVersionM,
+			want: []goBinary{
+				{
+					path:    "/home/alice/go/bin/gopls",
+					pkg:     "golang.org/x/tools/gopls",
+					module:  "golang.org/x/tools/gopls",
+					version: "v0.15.2",
+					deps:    []string{"github.com/BurntSushi/toml v1.2.1", "golang.org/x/mod v0.15.0"},
+				},
+				{
+					path:    "/home/alice/go/bin/staticcheck",
+					pkg:     "honnef.co/go/tools/cmd/staticcheck",
+					module:  "honnef.co/go/tools",
+					version: "v0.4.7",
+					deps:    []string{"golang.org/x/tools v0.12.1-0.20230815132531-74c255bcf846"},
+				},
+				{
+					path:    "/home/alice/go/bin/structlayout",
+					pkg:     "honnef.co/go/tools/cmd/structlayout",
+					module:  "honnef.co/go/tools",
+					version: "v0.4.7",
+				},
+			},
+		},
+		{
+			name:   "a locally built command",
+			output: "/home/alice/go/bin/tool: go1.22.1\n\tpath\texample.com/tool\n\tmod\texample.com/tool\t(devel)\t\n",
+			want:   []goBinary{{path: "/home/alice/go/bin/tool", pkg: "example.com/tool", module: "example.com/tool", version: "(devel)"}},
+		},
+		{
+			name:   "empty bin dir",
+			output: "",
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseGoVersion([]byte(tt.output))
+			if err != nil {
+				t.Fatalf("parseGoVersion: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseGoVersion = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
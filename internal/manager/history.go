@@ -0,0 +1,336 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"boxy/internal/history"
+)
+
+// historian is implemented by managers that keep their own log of package
+// changes, so changes made outside boxy can be shown too.
+type historian interface {
+	history() ([]history.Entry, error)
+}
+
+// SystemHistory returns the changes recorded in mgr's own logs, if it keeps
+// any. Unreadable logs are skipped.
+func SystemHistory(mgr PackageManager) []history.Entry {
+	if cached, ok := mgr.(*CachedManager); ok {
+		mgr = cached.PackageManager
+	}
+	if h, ok := mgr.(historian); ok {
+		if entries, err := h.history(); err == nil {
+			return entries
+		}
+	}
+	return nil
+}
+
+// Diff lists the packages whose installed version differs between two
+// lists, as from ListInstalled before and after an operation.
+func Diff(before, after []PackageInfo) []history.Change {
+	versions := make(map[string]string, len(before))
+	for _, pkg := range before {
+		versions[pkg.Name] = pkg.Version
+	}
+	var changes []history.Change
+	for _, pkg := range after {
+		old, ok := versions[pkg.Name]
+		delete(versions, pkg.Name)
+		if !ok || old != pkg.Version {
+			changes = append(changes, history.Change{Name: pkg.Name, Before: old, After: pkg.Version})
+		}
+	}
+	for _, pkg := range before {
+		if old, ok := versions[pkg.Name]; ok {
+			changes = append(changes, history.Change{Name: pkg.Name, Before: old})
+		}
+	}
+	return changes
+}
+
+// Recorded is what RunRecorded ran and how it went
+type Recorded struct {
+	Command    string
+	Output     string // empty unless run captured it
+	Err        error
+	HistoryErr error // recording the operation in the history failed
+}
+
+// RunRecorded runs mgr's command for action on pkgs and records it in the
+// history. run starts the command and returns its output, if it captures
+// any; it's where the caller feeds sudo a password or hands the command
+// the terminal.
+func RunRecorded(ctx context.Context, mgr PackageManager, action string, pkgs []string, run func(*exec.Cmd) (string, error)) Recorded {
+	rec := StartRecording(ctx, mgr)
+	command, output, err := rec.Run(action, pkgs, run)
+	return Recorded{Command: command, Output: output, Err: err, HistoryErr: rec.Finish(action, pkgs, nil, err)}
+}
+
+// Recording is an operation of one or more of a manager's commands being
+// recorded as one history entry, like an undo that removes some packages
+// and reinstalls others. What changed comes from listing the installed
+// packages before and after; if either listing fails, only the packages
+// asked for are recorded and the entry is marked so it isn't offered for
+// undo.
+type Recording struct {
+	ctx       context.Context
+	mgr       PackageManager
+	start     time.Time
+	before    []PackageInfo
+	beforeErr error
+	commands  []string
+	outputs   []string
+}
+
+// StartRecording lists mgr's installed packages, for an operation about to
+// start
+func StartRecording(ctx context.Context, mgr PackageManager) *Recording {
+	before, err := mgr.ListInstalled(ctx)
+	return &Recording{ctx: ctx, mgr: mgr, start: time.Now(), before: before, beforeErr: err}
+}
+
+// Run runs the manager's command for action on pkgs with run, noting its
+// command line and output for the entry
+func (r *Recording) Run(action string, pkgs []string, run func(*exec.Cmd) (string, error)) (command, output string, err error) {
+	cmd := r.mgr.Command(r.ctx, action, pkgs...)
+	command = strings.Join(cmd.Args, " ")
+	output, err = run(cmd)
+	r.commands = append(r.commands, command)
+	if output != "" {
+		r.outputs = append(r.outputs, output)
+	}
+	return command, output, err
+}
+
+// Finish records the operation in the history as action on pkgs, failed
+// with err if it's set. undoes is the time of the operation this one
+// reverses, for undos.
+func (r *Recording) Finish(action string, pkgs []string, undoes *time.Time, err error) error {
+	after, afterErr := r.mgr.ListInstalled(r.ctx)
+	entry := history.Entry{
+		Time:    r.start,
+		Source:  "boxy",
+		Manager: r.mgr.Name(),
+		Action:  action,
+		Command: strings.Join(r.commands, " && "),
+		Output:  strings.Join(r.outputs, "\n"),
+		Undoes:  undoes,
+	}
+	if r.beforeErr != nil || afterErr != nil {
+		// Diffing against a missing list would record every package as
+		// installed or removed, and an undo would act on all of them
+		entry.Packages = changes(nil, nil, pkgs)
+		entry.ChangesUnknown = true
+	} else {
+		entry.Packages = changes(r.before, after, pkgs)
+	}
+	if err != nil {
+		entry.Error = err.Error()
+		entry.ExitCode = -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			entry.ExitCode = exitErr.ExitCode()
+		}
+	}
+	return history.Append(entry)
+}
+
+// changes lists what an operation changed, plus the packages it was asked
+// to act on even if they didn't change (it failed, say)
+func changes(before, after []PackageInfo, pkgs []string) []history.Change {
+	changes := Diff(before, after)
+	changed := make(map[string]bool, len(changes))
+	for _, c := range changes {
+		changed[c.Name] = true
+	}
+	versions := make(map[string]string, len(after))
+	for _, pkg := range after {
+		versions[pkg.Name] = pkg.Version
+	}
+	for _, pkg := range pkgs {
+		if !changed[pkg] {
+			changes = append(changes, history.Change{Name: pkg, Before: versions[pkg], After: versions[pkg]})
+		}
+	}
+	return changes
+}
+
+// aptHistoryPackage matches one entry of a history.log package list:
+// "name:arch (version[, automatic])" or, for upgrades, "name:arch (old, new)"
+var aptHistoryPackage = regexp.MustCompile(`([^\s,]+) \(([^)]*)\)`)
+
+// history reads apt's history.log, including rotated copies. Each run of
+// apt is a stanza with Start-Date, Commandline and the packages it
+// installed, upgraded or removed.
+func (a *AptManager) history() ([]history.Entry, error) {
+	files, err := filepath.Glob("/var/log/apt/history.log*")
+	if err != nil {
+		return nil, err
+	}
+	var entries []history.Entry
+	for _, path := range files {
+		data, err := readMaybeGzip(path)
+		if err != nil {
+			continue
+		}
+		stanzas, _ := parseControl(bytes.NewReader(data))
+		for _, s := range stanzas {
+			if e, ok := aptHistoryEntry(s); ok {
+				entries = append(entries, e)
+			}
+		}
+	}
+	return entries, nil
+}
+
+func aptHistoryEntry(s controlStanza) (history.Entry, bool) {
+	when, err := time.ParseInLocation("2006-01-02  15:04:05", s["Start-Date"], time.Local)
+	if err != nil {
+		return history.Entry{}, false
+	}
+	e := history.Entry{Time: when, Source: "apt", Manager: "apt", Command: s["Commandline"]}
+
+	for _, field := range []string{"Install", "Reinstall", "Upgrade", "Downgrade", "Remove", "Purge"} {
+		for _, match := range aptHistoryPackage.FindAllStringSubmatch(s[field], -1) {
+			name, _, _ := strings.Cut(match[1], ":")
+			versions := strings.Split(match[2], ", ")
+			change := history.Change{Name: name}
+			switch field {
+			case "Install", "Reinstall":
+				change.After = versions[0]
+			case "Remove", "Purge":
+				change.Before = versions[0]
+			default:
+				change.Before = versions[0]
+				if len(versions) > 1 {
+					change.After = versions[1]
+				}
+			}
+			e.Packages = append(e.Packages, change)
+		}
+		if e.Action == "" && s[field] != "" {
+			e.Action = aptHistoryAction(field)
+		}
+	}
+	if e.Action == "" {
+		return history.Entry{}, false
+	}
+
+	// The command line names the operation better than the fields do: an
+	// upgrade that pulls in new dependencies also has an Install field
+	fields := strings.Fields(e.Command)
+	for _, word := range fields[min(1, len(fields)):] {
+		if action, ok := aptCommandActions[word]; ok {
+			e.Action = action
+			break
+		}
+	}
+
+	if msg := s["Error"]; msg != "" {
+		e.Error = msg
+		e.ExitCode = 1
+	}
+	return e, true
+}
+
+var aptCommandActions = map[string]string{
+	"install":      "install",
+	"reinstall":    "install",
+	"remove":       "uninstall",
+	"purge":        "uninstall",
+	"autoremove":   "cleanup",
+	"upgrade":      "upgrade",
+	"full-upgrade": "upgrade",
+	"dist-upgrade": "upgrade",
+}
+
+func aptHistoryAction(field string) string {
+	switch field {
+	case "Install", "Reinstall":
+		return "install"
+	case "Remove", "Purge":
+		return "uninstall"
+	}
+	return "upgrade"
+}
+
+// brewReceipt is the part of a keg's INSTALL_RECEIPT.json we read
+type brewReceipt struct {
+	Time int64 `json:"time"`
+}
+
+// history reads the install receipt brew leaves in every keg. brew keeps no
+// log, so only installs of kegs still present are known.
+func (b *BrewManager) history() ([]history.Entry, error) {
+	cellar, err := b.cellar(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	receipts, err := filepath.Glob(filepath.Join(cellar, "*", "*", "INSTALL_RECEIPT.json"))
+	if err != nil {
+		return nil, err
+	}
+	var entries []history.Entry
+	for _, path := range receipts {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var receipt brewReceipt
+		if err := json.Unmarshal(data, &receipt); err != nil || receipt.Time == 0 {
+			continue
+		}
+		keg := filepath.Dir(path)
+		entries = append(entries, history.Entry{
+			Time:     time.Unix(receipt.Time, 0),
+			Source:   "brew",
+			Manager:  "brew",
+			Action:   "install",
+			Packages: []history.Change{{Name: filepath.Base(filepath.Dir(keg)), After: filepath.Base(keg)}},
+		})
+	}
+	return entries, nil
+}
+
+// versionPinner is implemented by managers that can install a given
+// version of a package.
+type versionPinner interface {
+	pinned(name, version string) string
+}
+
+// Pinned returns the argument that installs version of name with mgr, or
+// just name if the manager only installs the current version.
+func Pinned(mgr PackageManager, name, version string) string {
+	if cached, ok := mgr.(*CachedManager); ok {
+		mgr = cached.PackageManager
+	}
+	if p, ok := mgr.(versionPinner); ok && version != "" {
+		return p.pinned(name, version)
+	}
+	return name
+}
+
+// CanPin reports whether mgr can install a given version of a package.
+// Others only install the current one, which leaves an installed package
+// as it is.
+func CanPin(mgr PackageManager) bool {
+	if cached, ok := mgr.(*CachedManager); ok {
+		mgr = cached.PackageManager
+	}
+	_, ok := mgr.(versionPinner)
+	return ok
+}
+
+func (a *AptManager) pinned(name, version string) string {
+	return name + "=" + version
+}
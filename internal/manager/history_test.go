@@ -0,0 +1,178 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"boxy/internal/history"
+)
+
+// aptHistoryLog is a few stanzas of /var/log/apt/history.log
+const aptHistoryLog = `
+Start-Date: 2024-03-02  10:15:42
+Commandline: apt-get install -y jq
+Requested-By: alice (1000)
+Install: libjq1:amd64 (1.6-2.1, automatic), libonig5:amd64 (6.9.8-1, automatic), jq:amd64 (1.6-2.1)
+End-Date: 2024-03-02  10:15:44
+
+Start-Date: 2024-03-05  08:01:10
+Commandline: apt full-upgrade
+Install: linux-image-6.1.0-18-amd64:amd64 (6.1.76-1, automatic)
+Upgrade: curl:amd64 (7.88.1-10+deb12u4, 7.88.1-10+deb12u5), libcurl4:amd64 (7.88.1-10+deb12u4, 7.88.1-10+deb12u5)
+End-Date: 2024-03-05  08:02:30
+
+Start-Date: 2024-03-06  19:40:03
+Commandline: apt purge jq
+Purge: jq:amd64 (1.6-2.1)
+Error: Sub-process /usr/bin/dpkg returned an error code (1)
+End-Date: 2024-03-06  19:40:04
+
+Start-Date: 2024-03-07  12:00:00
+Commandline: apt-mark hold curl
+End-Date: 2024-03-07  12:00:00
+
+Start-Date: 2024-03-08  09:30:00
+Install: ncdu:amd64 (1.18-0.2)
+End-Date: 2024-03-08  09:30:01
+`
+
+func TestAptHistoryEntry(t *testing.T) {
+	at := func(s string) time.Time {
+		when, err := time.ParseInLocation("2006-01-02 15:04:05", s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return when
+	}
+	want := []history.Entry{
+		{
+			Time: at("2024-03-02 10:15:42"), Source: "apt", Manager: "apt", Action: "install",
+			Command: "apt-get install -y jq",
+			Packages: []history.Change{
+				{Name: "libjq1", After: "1.6-2.1"},
+				{Name: "libonig5", After: "6.9.8-1"},
+				{Name: "jq", After: "1.6-2.1"},
+			},
+		},
+		{
+			Time: at("2024-03-05 08:01:10"), Source: "apt", Manager: "apt", Action: "upgrade",
+			Command: "apt full-upgrade",
+			Packages: []history.Change{
+				{Name: "linux-image-6.1.0-18-amd64", After: "6.1.76-1"},
+				{Name: "curl", Before: "7.88.1-10+deb12u4", After: "7.88.1-10+deb12u5"},
+				{Name: "libcurl4", Before: "7.88.1-10+deb12u4", After: "7.88.1-10+deb12u5"},
+			},
+		},
+		{
+			Time: at("2024-03-06 19:40:03"), Source: "apt", Manager: "apt", Action: "uninstall",
+			Command:  "apt purge jq",
+			Packages: []history.Change{{Name: "jq", Before: "1.6-2.1"}},
+			Error:    "Sub-process /usr/bin/dpkg returned an error code (1)",
+			ExitCode: 1,
+		},
+		// apt-mark's stanza changes no packages and is skipped
+		{
+			Time: at("2024-03-08 09:30:00"), Source: "apt", Manager: "apt", Action: "install",
+			Packages: []history.Change{{Name: "ncdu", After: "1.18-0.2"}},
+		},
+	}
+
+	stanzas, err := parseControl(strings.NewReader(aptHistoryLog))
+	if err != nil {
+		t.Fatalf("parseControl: %v", err)
+	}
+	var got []history.Entry
+	for _, s := range stanzas {
+		if e, ok := aptHistoryEntry(s); ok {
+			got = append(got, e)
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("entry %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestAptHistoryEntryBadDate(t *testing.T) {
+	s := controlStanza{"Start-Date": "yesterday", "Install": "jq:amd64 (1.6-2.1)"}
+	if e, ok := aptHistoryEntry(s); ok {
+		t.Errorf("aptHistoryEntry = %+v, want it skipped", e)
+	}
+}
+
+// fakeManager lists installed and builds commands it never runs, with the
+// action as the program
+type fakeManager struct {
+	PackageManager
+	installed []PackageInfo
+}
+
+func (f *fakeManager) Name() string { return "fake" }
+
+func (f *fakeManager) ListInstalled(ctx context.Context) ([]PackageInfo, error) {
+	return f.installed, nil
+}
+
+func (f *fakeManager) Command(ctx context.Context, action string, pkgs ...string) *exec.Cmd {
+	return exec.Command(action, pkgs...)
+}
+
+func TestRecording(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	mgr := &fakeManager{installed: []PackageInfo{{Name: "jq", Version: "1.6"}, {Name: "curl", Version: "7.88.1-11"}}}
+
+	// An undo that removes jq, then fails to reinstall an older curl
+	rec := StartRecording(context.Background(), mgr)
+	command, output, err := rec.Run("uninstall", []string{"jq"}, func(cmd *exec.Cmd) (string, error) {
+		mgr.installed = mgr.installed[1:]
+		return "Removing jq", nil
+	})
+	if command != "uninstall jq" || output != "Removing jq" || err != nil {
+		t.Fatalf("Run = %q, %q, %v", command, output, err)
+	}
+	reinstallErr := errors.New("no such version")
+	if _, _, err := rec.Run("install", []string{"curl=7.88.1-10"}, func(cmd *exec.Cmd) (string, error) {
+		return "", reinstallErr
+	}); err != reinstallErr {
+		t.Fatalf("Run error = %v, want %v", err, reinstallErr)
+	}
+	undoes := time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC)
+	if err := rec.Finish("undo", []string{"jq", "curl"}, &undoes, reinstallErr); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+
+	entries, err := history.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("recorded %d entries, want 1: %+v", len(entries), entries)
+	}
+	e := entries[0]
+	want := history.Entry{
+		Source:   "boxy",
+		Manager:  "fake",
+		Action:   "undo",
+		Command:  "uninstall jq && install curl=7.88.1-10",
+		Output:   "Removing jq",
+		Packages: []history.Change{{Name: "jq", Before: "1.6"}, {Name: "curl", Before: "7.88.1-11", After: "7.88.1-11"}},
+		ExitCode: -1,
+		Error:    "no such version",
+	}
+	if e.Undoes == nil || !e.Undoes.Equal(undoes) {
+		t.Errorf("Undoes = %v, want %v", e.Undoes, undoes)
+	}
+	e.Time, e.Undoes = time.Time{}, nil
+	if !reflect.DeepEqual(e, want) {
+		t.Errorf("entry = %+v, want %+v", e, want)
+	}
+}
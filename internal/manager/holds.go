@@ -0,0 +1,64 @@
+package manager
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+)
+
+// holdLister is implemented by managers that can keep packages at their
+// installed version.
+type holdLister interface {
+	holds(ctx context.Context) ([]string, error)
+}
+
+// Holds returns the packages mgr won't upgrade, or nil if it can't hold
+// packages.
+func Holds(ctx context.Context, mgr PackageManager) ([]string, error) {
+	if cached, ok := mgr.(*CachedManager); ok {
+		mgr = cached.PackageManager
+	}
+	if h, ok := mgr.(holdLister); ok {
+		return h.holds(ctx)
+	}
+	return nil, nil
+}
+
+func (a *AptManager) holds(ctx context.Context) ([]string, error) {
+	output, err := exec.CommandContext(ctx, "apt-mark", "showhold").Output()
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(output)), nil
+}
+
+// holds lists pinned formulae, which brew upgrade leaves alone
+func (b *BrewManager) holds(ctx context.Context) ([]string, error) {
+	output, err := exec.CommandContext(ctx, "brew", "list", "--pinned").Output()
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(output)), nil
+}
+
+// manualMarker is implemented by managers that tell packages installed on
+// request from those pulled in as dependencies, and can mark a package
+// either way with the "manual" and "auto" actions.
+type manualMarker interface {
+	marksManual() bool
+}
+
+// MarksManual reports whether mgr can mark packages as installed manually
+// or as dependencies. ListManuallyInstalled of other managers may just be
+// everything installed.
+func MarksManual(mgr PackageManager) bool {
+	if cached, ok := mgr.(*CachedManager); ok {
+		mgr = cached.PackageManager
+	}
+	m, ok := mgr.(manualMarker)
+	return ok && m.marksManual()
+}
+
+func (a *AptManager) marksManual() bool {
+	return true
+}
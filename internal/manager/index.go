@@ -0,0 +1,147 @@
+package manager
+
+import (
+	"sort"
+	"strings"
+)
+
+// Index is an in-memory catalog of every package a manager can install,
+// built from its local metadata so searches don't need to shell out.
+type Index struct {
+	packages []PackageInfo
+	// lowercased name and description per package, matched on every search
+	names []string
+	descs []string
+}
+
+// indexer is implemented by managers that can build a local Index.
+type indexer interface {
+	index() (*Index, error)
+}
+
+// WarmIndex builds mgr's search index ahead of the first search, if the
+// manager has one, and returns it (nil otherwise). It blocks, so callers
+// run it in the background.
+func WarmIndex(mgr PackageManager) *Index {
+	if cached, ok := mgr.(*CachedManager); ok {
+		mgr = cached.PackageManager
+	}
+	if idx, ok := mgr.(indexer); ok {
+		if index, err := idx.index(); err == nil {
+			return index
+		}
+	}
+	return nil
+}
+
+func newIndex(packages []PackageInfo) *Index {
+	sort.Slice(packages, func(i, j int) bool {
+		return packages[i].Name < packages[j].Name
+	})
+	idx := &Index{
+		packages: packages,
+		names:    make([]string, len(packages)),
+		descs:    make([]string, len(packages)),
+	}
+	for i, pkg := range packages {
+		idx.names[i] = strings.ToLower(pkg.Name)
+		idx.descs[i] = strings.ToLower(pkg.Description)
+	}
+	return idx
+}
+
+// Len returns the number of packages in the index.
+func (idx *Index) Len() int {
+	return len(idx.packages)
+}
+
+// Lookup returns the catalog entry for the named package.
+func (idx *Index) Lookup(name string) (PackageInfo, bool) {
+	i := sort.Search(len(idx.packages), func(i int) bool {
+		return idx.packages[i].Name >= name
+	})
+	if i < len(idx.packages) && idx.packages[i].Name == name {
+		return idx.packages[i], true
+	}
+	return PackageInfo{}, false
+}
+
+// Search returns the packages whose name or description contains every
+// word of query, best matches first.
+func (idx *Index) Search(query string) []PackageInfo {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return nil
+	}
+
+	var results []PackageInfo
+	for i, pkg := range idx.packages {
+		if idx.matchesAll(i, terms) {
+			results = append(results, pkg)
+		}
+	}
+	return Rank(results, query)
+}
+
+// Match tiers used by Rank, best first.
+const (
+	matchExact = iota
+	matchPrefix
+	matchName
+	matchDescription
+)
+
+// Rank orders results by how well they match query: exact name, then name
+// prefix, then name substring, then description-only matches. Within a tier
+// shorter names come first, so "git" ranks above "git-annex-remote-rclone".
+func Rank(results []PackageInfo, query string) []PackageInfo {
+	q := strings.ToLower(strings.TrimSpace(query))
+	sort.SliceStable(results, func(i, j int) bool {
+		si, sj := MatchScore(results[i], q), MatchScore(results[j], q)
+		if si != sj {
+			return si < sj
+		}
+		if len(results[i].Name) != len(results[j].Name) {
+			return len(results[i].Name) < len(results[j].Name)
+		}
+		return results[i].Name < results[j].Name
+	})
+	return results
+}
+
+// MatchScore returns the match tier of pkg for a lowercased query.
+func MatchScore(pkg PackageInfo, query string) int {
+	name := strings.ToLower(pkg.Name)
+	switch {
+	case name == query:
+		return matchExact
+	case strings.HasPrefix(name, query):
+		return matchPrefix
+	case strings.Contains(name, query):
+		return matchName
+	}
+	// Multi-word queries count as name matches if every word is in the name
+	terms := strings.Fields(query)
+	if len(terms) > 1 {
+		all := true
+		for _, term := range terms {
+			if !strings.Contains(name, term) {
+				all = false
+				break
+			}
+		}
+		if all {
+			return matchName
+		}
+	}
+	return matchDescription
+}
+
+func (idx *Index) matchesAll(i int, terms []string) bool {
+	for _, term := range terms {
+		if !strings.Contains(idx.names[i], term) && !strings.Contains(idx.descs[i], term) {
+			return false
+		}
+	}
+	return true
+}
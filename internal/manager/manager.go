@@ -3,6 +3,7 @@ package manager
 import (
 	"context"
 	"os/exec"
+	"time"
 )
 
 type PackageInfo struct {
@@ -10,6 +11,30 @@ type PackageInfo struct {
 	Version     string
 	Description string
 	Installed   bool
+	Manager     string // name of the manager the package comes from
+
+	// Extended metadata, filled in by GetInfo where the manager provides it.
+	LongDescription  string
+	Section          string
+	Priority         string
+	Maintainer       string
+	Homepage         string
+	InstalledSize    int64 // bytes
+	Depends          []string
+	Recommends       []string
+	Suggests         []string
+	Source           string // source package the binary was built from
+	InstalledVersion string
+	CandidateVersion string
+	License          string
+	Caveats          string
+	BuildDepends     []string
+	// InstalledVersions lists every installed version (brew can keep several kegs).
+	InstalledVersions  []string
+	InstalledOnRequest bool
+	PouredFromBottle   bool
+	InstallTime        time.Time
+	UpdateTime         time.Time // when the installed version last changed
 }
 
 type PackageManager interface {
@@ -22,6 +47,13 @@ type PackageManager interface {
 	GetInfo(ctx context.Context, pkg string) (PackageInfo, error)
 	ListInstalled(ctx context.Context) ([]PackageInfo, error)
 	ListManuallyInstalled(ctx context.Context) ([]PackageInfo, error)
-	Command(ctx context.Context, action string, pkg string) *exec.Cmd
+	// Command builds the command for an action on pkgs ("install",
+	// "uninstall", "upgrade", "hold" and "unhold", which stop and allow
+	// upgrades, "manual" and "auto", which mark packages as installed on
+	// request or as dependencies, or "cleanup", which removes unneeded
+	// packages and takes none).
+	Command(ctx context.Context, action string, pkgs ...string) *exec.Cmd
 	NeedsSudo() bool
+	Files(ctx context.Context, pkg string) ([]string, error)
+	Owner(ctx context.Context, path string) (string, error)
 }
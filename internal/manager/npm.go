@@ -0,0 +1,296 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// NpmManager manages packages installed globally with `npm install -g`.
+// Everything about an installed package is read from its package.json
+// under the global root; only packages that aren't installed are looked
+// up in the registry. The global prefix must be writable by the user, as
+// with `npm config set prefix ~/.local`, since boxy doesn't run npm under
+// sudo.
+type NpmManager struct {
+	userTool
+
+	rootOnce sync.Once
+	root     string // global node_modules
+	rootErr  error
+}
+
+// NewNpmManager returns the manager for npm's global packages
+func NewNpmManager() *NpmManager {
+	n := &NpmManager{}
+	n.self = n
+	return n
+}
+
+func (n *NpmManager) Name() string {
+	return "npm"
+}
+
+func (n *NpmManager) IsAvailable() bool {
+	_, err := exec.LookPath("npm")
+	return err == nil
+}
+
+// globalRoot asks npm for its global node_modules, once
+func (n *NpmManager) globalRoot() (string, error) {
+	n.rootOnce.Do(func() {
+		output, err := exec.Command("npm", "root", "-g").Output()
+		if err != nil {
+			n.rootErr = fmt.Errorf("npm root -g: %w", err)
+			return
+		}
+		n.root = strings.TrimSpace(string(output))
+	})
+	return n.root, n.rootErr
+}
+
+// binDir is where npm links the commands of global packages: prefix/bin,
+// with the root at prefix/lib/node_modules
+func (n *NpmManager) binDir() (string, error) {
+	root, err := n.globalRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(filepath.Dir(root)), "bin"), nil
+}
+
+// StatePaths returns the global node_modules, which gains or loses an
+// entry whenever a package is installed or removed
+func (n *NpmManager) StatePaths() []string {
+	root, err := n.globalRoot()
+	if err != nil {
+		return nil
+	}
+	return []string{root}
+}
+
+// Search uses npm's search, which answers from npm's cache when it has
+// the query cached and asks the registry otherwise
+func (n *NpmManager) Search(ctx context.Context, query string) ([]PackageInfo, error) {
+	output, err := exec.CommandContext(ctx, "npm", "search", "--json", "--prefer-offline", query).Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseNpmSearch(output)
+}
+
+// parseNpmSearch reads the matches `npm search --json` prints
+func parseNpmSearch(output []byte) ([]PackageInfo, error) {
+	var found []struct {
+		Name        string `json:"name"`
+		Version     string `json:"version"`
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal(output, &found); err != nil {
+		return nil, fmt.Errorf("reading npm search: %w", err)
+	}
+	results := make([]PackageInfo, len(found))
+	for i, pkg := range found {
+		results[i] = PackageInfo{Name: pkg.Name, Version: pkg.Version, Description: pkg.Description}
+	}
+	return results, nil
+}
+
+// ListInstalled reads `npm ls -g --json --depth=0`
+func (n *NpmManager) ListInstalled(ctx context.Context) ([]PackageInfo, error) {
+	// npm ls exits non-zero over problems like missing peer dependencies,
+	// but still prints the tree
+	output, err := exec.CommandContext(ctx, "npm", "ls", "-g", "--json", "--depth=0").Output()
+	if len(output) == 0 && err != nil {
+		return nil, err
+	}
+	return parseNpmLs(output)
+}
+
+// parseNpmLs reads the top level of `npm ls --json`'s dependency tree
+func parseNpmLs(output []byte) ([]PackageInfo, error) {
+	var tree struct {
+		Dependencies map[string]struct {
+			Version string `json:"version"`
+		} `json:"dependencies"`
+	}
+	if err := json.Unmarshal(output, &tree); err != nil {
+		return nil, fmt.Errorf("reading npm ls: %w", err)
+	}
+	var packages []PackageInfo
+	for name, dep := range tree.Dependencies {
+		packages = append(packages, PackageInfo{Name: name, Version: dep.Version, InstalledVersion: dep.Version, Installed: true})
+	}
+	sort.Slice(packages, func(i, j int) bool { return packages[i].Name < packages[j].Name })
+	return packages, nil
+}
+
+func (n *NpmManager) IsInstalled(ctx context.Context, pkg string) (bool, error) {
+	_, err := n.packageJSON(pkg)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// npmPackage is the part of a package.json boxy reads
+type npmPackage struct {
+	Name         string            `json:"name"`
+	Version      string            `json:"version"`
+	Description  string            `json:"description"`
+	Homepage     string            `json:"homepage"`
+	License      interface{}       `json:"license"` // a string, or {"type": ...} in old packages
+	Author       interface{}       `json:"author"`  // a string or {"name": ...}
+	Dependencies map[string]string `json:"dependencies"`
+	Bin          interface{}       `json:"bin"` // a path, or command names to paths
+}
+
+// packageJSON reads an installed package's package.json
+func (n *NpmManager) packageJSON(pkg string) (npmPackage, error) {
+	root, err := n.globalRoot()
+	if err != nil {
+		return npmPackage{}, err
+	}
+	raw, err := os.ReadFile(filepath.Join(root, pkg, "package.json"))
+	if err != nil {
+		return npmPackage{}, err
+	}
+	var p npmPackage
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return npmPackage{}, fmt.Errorf("reading %s's package.json: %w", pkg, err)
+	}
+	return p, nil
+}
+
+// GetInfo reads an installed package's package.json, or asks the registry
+// about one that isn't installed
+func (n *NpmManager) GetInfo(ctx context.Context, pkg string) (PackageInfo, error) {
+	p, err := n.packageJSON(pkg)
+	if os.IsNotExist(err) {
+		output, err := exec.CommandContext(ctx, "npm", "view", "--json", pkg).Output()
+		if err != nil {
+			return PackageInfo{}, fmt.Errorf("npm view %s: %w", pkg, err)
+		}
+		if err := json.Unmarshal(output, &p); err != nil {
+			return PackageInfo{}, fmt.Errorf("reading npm view: %w", err)
+		}
+		info := p.info()
+		info.CandidateVersion = p.Version
+		return info, nil
+	}
+	if err != nil {
+		return PackageInfo{}, err
+	}
+	info := p.info()
+	info.Installed = true
+	info.InstalledVersion = p.Version
+	return info, nil
+}
+
+func (p npmPackage) info() PackageInfo {
+	info := PackageInfo{
+		Name:        p.Name,
+		Version:     p.Version,
+		Description: p.Description,
+		Homepage:    p.Homepage,
+		License:     nameOf(p.License, "type"),
+		Maintainer:  nameOf(p.Author, "name"),
+	}
+	for dep, req := range p.Dependencies {
+		info.Depends = append(info.Depends, dep+" "+req)
+	}
+	sort.Strings(info.Depends)
+	return info
+}
+
+// nameOf reads a package.json field that's either a string or an object
+// with the string under key
+func nameOf(v interface{}, key string) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		s, _ := v[key].(string)
+		return s
+	}
+	return ""
+}
+
+// commands lists the command names the package links into the bin dir. A
+// bare path in "bin" is named after the package, minus its scope.
+func (p npmPackage) commands() []string {
+	switch bin := p.Bin.(type) {
+	case string:
+		name := p.Name
+		if i := strings.LastIndex(name, "/"); i >= 0 {
+			name = name[i+1:]
+		}
+		return []string{name}
+	case map[string]interface{}:
+		var names []string
+		for name := range bin {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names
+	}
+	return nil
+}
+
+// Files lists the commands the package put on PATH
+func (n *NpmManager) Files(ctx context.Context, pkg string) ([]string, error) {
+	p, err := n.packageJSON(pkg)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not installed with npm: %w", pkg, err)
+	}
+	dir, err := n.binDir()
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, name := range p.commands() {
+		files = append(files, filepath.Join(dir, name))
+	}
+	return files, nil
+}
+
+// Owner finds the global package that put path on PATH
+func (n *NpmManager) Owner(ctx context.Context, path string) (string, error) {
+	installed, err := n.ListInstalled(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, pkg := range installed {
+		files, _ := n.Files(ctx, pkg.Name)
+		for _, file := range files {
+			if sameFile(file, path) {
+				return pkg.Name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no npm package owns %s", path)
+}
+
+func (n *NpmManager) Command(ctx context.Context, action string, pkgs ...string) *exec.Cmd {
+	switch action {
+	case "install", "uninstall":
+		return exec.CommandContext(ctx, "npm", append([]string{action, "-g"}, pkgs...)...)
+	case "upgrade":
+		latest := make([]string, len(pkgs))
+		for i, pkg := range pkgs {
+			latest[i] = pkg + "@latest"
+		}
+		return exec.CommandContext(ctx, "npm", append([]string{"install", "-g"}, latest...)...)
+	}
+	return unsupported(ctx, "npm", action)
+}
+
+func (n *NpmManager) pinned(name, version string) string {
+	return name + "@" + version
+}
@@ -0,0 +1,123 @@
+package manager
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseNpmLs(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    []PackageInfo
+		wantErr bool
+	}{
+		{
+			name: "global packages, sorted",
+			output: `{
+  "name": "lib",
+  "dependencies": {
+    "typescript": {"version": "5.4.2", "overridden": false},
+    "@angular/cli": {"version": "17.3.0", "overridden": false},
+    "npm": {"version": "10.5.0", "overridden": false}
+  }
+}`,
+			want: []PackageInfo{
+				{Name: "@angular/cli", Version: "17.3.0", InstalledVersion: "17.3.0", Installed: true},
+				{Name: "npm", Version: "10.5.0", InstalledVersion: "10.5.0", Installed: true},
+				{Name: "typescript", Version: "5.4.2", InstalledVersion: "5.4.2", Installed: true},
+			},
+		},
+		{
+			// npm still prints the tree when it exits non-zero over a problem
+			name: "with problems",
+			output: `{
+  "problems": ["missing: rxjs@^7.0.0, required by @angular/cli@17.3.0"],
+  "dependencies": {"@angular/cli": {"version": "17.3.0", "problems": ["missing: rxjs@^7.0.0"]}}
+}`,
+			want: []PackageInfo{{Name: "@angular/cli", Version: "17.3.0", InstalledVersion: "17.3.0", Installed: true}},
+		},
+		{
+			name:   "nothing installed",
+			output: `{"name": "lib"}`,
+			want:   nil,
+		},
+		{
+			name:    "not JSON",
+			output:  "npm ERR! code ELSPROBLEMS\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseNpmLs([]byte(tt.output))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseNpmLs error = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseNpmLs = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNpmSearch(t *testing.T) {
+	output := `[
+  {"name": "prettier", "scope": "unscoped", "version": "3.2.5", "description": "Prettier is an opinionated code formatter", "keywords": ["formatter"], "date": "2024-02-04T06:24:14.000Z"},
+  {"name": "@prettier/plugin-xml", "scope": "prettier", "version": "3.3.1", "description": "prettier plugin for XML"}
+]`
+	want := []PackageInfo{
+		{Name: "prettier", Version: "3.2.5", Description: "Prettier is an opinionated code formatter"},
+		{Name: "@prettier/plugin-xml", Version: "3.3.1", Description: "prettier plugin for XML"},
+	}
+	got, err := parseNpmSearch([]byte(output))
+	if err != nil {
+		t.Fatalf("parseNpmSearch: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseNpmSearch = %+v, want %+v", got, want)
+	}
+	if got, err := parseNpmSearch([]byte("[]")); err != nil || len(got) != 0 {
+		t.Errorf("parseNpmSearch of no matches = %+v, %v", got, err)
+	}
+}
+
+func TestNpmPackage(t *testing.T) {
+	tests := []struct {
+		name     string
+		pkg      npmPackage
+		license  string
+		author   string
+		commands []string
+	}{
+		{
+			name:     "strings and a bare bin path",
+			pkg:      npmPackage{Name: "@vue/cli", License: "MIT", Author: "Evan You", Bin: "bin/vue.js"},
+			license:  "MIT",
+			author:   "Evan You",
+			commands: []string{"cli"},
+		},
+		{
+			name:     "objects and named commands",
+			pkg:      npmPackage{Name: "typescript", License: map[string]interface{}{"type": "Apache-2.0"}, Author: map[string]interface{}{"name": "Microsoft Corp.", "email": "x@example.com"}, Bin: map[string]interface{}{"tsserver": "bin/tsserver", "tsc": "bin/tsc"}},
+			license:  "Apache-2.0",
+			author:   "Microsoft Corp.",
+			commands: []string{"tsc", "tsserver"},
+		},
+		{
+			name: "no commands",
+			pkg:  npmPackage{Name: "left-pad"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := tt.pkg.info()
+			if info.License != tt.license || info.Maintainer != tt.author {
+				t.Errorf("info = license %q, maintainer %q, want %q, %q", info.License, info.Maintainer, tt.license, tt.author)
+			}
+			if got := tt.pkg.commands(); !reflect.DeepEqual(got, tt.commands) {
+				t.Errorf("commands = %q, want %q", got, tt.commands)
+			}
+		})
+	}
+}
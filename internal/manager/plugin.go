@@ -0,0 +1,324 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PluginPrefix starts the name of every plugin executable: a program
+// called boxy-manager-<name> on PATH is offered as the manager <name>.
+const PluginPrefix = "boxy-manager-"
+
+// PluginProtocol is the protocol version boxy speaks. Plugins report the
+// version they speak in their capabilities and are skipped if it differs.
+const PluginProtocol = 1
+
+// pluginTimeout bounds each query a plugin answers. Commands built by the
+// plugin aren't subject to it.
+const pluginTimeout = 2 * time.Minute
+
+// pluginRequest is written to the plugin's stdin, one per run. Method is
+// one of capabilities, search, list, list_manual, info, files, owner or
+// command.
+type pluginRequest struct {
+	Protocol int      `json:"protocol"`
+	Method   string   `json:"method"`
+	Query    string   `json:"query,omitempty"`    // search
+	Package  string   `json:"package,omitempty"`  // info, files
+	Path     string   `json:"path,omitempty"`     // owner
+	Action   string   `json:"action,omitempty"`   // command: install, uninstall, upgrade, cleanup, hold or unhold
+	Packages []string `json:"packages,omitempty"` // command
+}
+
+// pluginResponse is what the plugin writes to stdout. Only the fields for
+// the method asked are read; a non-empty Error fails the request.
+type pluginResponse struct {
+	Error string `json:"error,omitempty"`
+
+	// capabilities
+	Protocol   int      `json:"protocol"`
+	Methods    []string `json:"methods"`
+	NeedsSudo  bool     `json:"needs_sudo"`
+	StatePaths []string `json:"state_paths"`
+
+	Packages []pluginPackage `json:"packages"` // search, list, list_manual
+	Package  pluginPackage   `json:"package"`  // info
+	Files    []string        `json:"files"`    // files
+	Owner    string          `json:"owner"`    // owner
+	Argv     []string        `json:"argv"`     // command
+}
+
+// pluginPackage is a package as plugins describe it
+type pluginPackage struct {
+	Name             string   `json:"name"`
+	Version          string   `json:"version"`
+	Description      string   `json:"description"`
+	Installed        bool     `json:"installed"`
+	LongDescription  string   `json:"long_description"`
+	Homepage         string   `json:"homepage"`
+	License          string   `json:"license"`
+	Maintainer       string   `json:"maintainer"`
+	InstalledSize    int64    `json:"installed_size"` // bytes
+	Depends          []string `json:"depends"`
+	InstalledVersion string   `json:"installed_version"`
+	CandidateVersion string   `json:"candidate_version"`
+}
+
+func (p pluginPackage) info() PackageInfo {
+	return PackageInfo{
+		Name:             p.Name,
+		Version:          p.Version,
+		Description:      p.Description,
+		Installed:        p.Installed,
+		LongDescription:  p.LongDescription,
+		Homepage:         p.Homepage,
+		License:          p.License,
+		Maintainer:       p.Maintainer,
+		InstalledSize:    p.InstalledSize,
+		Depends:          p.Depends,
+		InstalledVersion: p.InstalledVersion,
+		CandidateVersion: p.CandidateVersion,
+	}
+}
+
+// PluginManager is a PackageManager backed by an external executable that
+// answers JSON requests on stdin and stdout. Queries are answered by the
+// plugin directly; for installs and the like it returns the command to
+// run, so boxy can show its output and ask for the sudo password.
+type PluginManager struct {
+	name string
+	path string
+
+	once sync.Once
+	caps pluginResponse
+	err  error
+}
+
+// FindPlugins returns a manager for each boxy-manager-<name> executable on
+// PATH. Earlier PATH entries win when two share a name.
+func FindPlugins() []*PluginManager {
+	var plugins []*PluginManager
+	seen := make(map[string]bool)
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		matches, _ := filepath.Glob(filepath.Join(dir, PluginPrefix+"*"))
+		for _, path := range matches {
+			name := strings.TrimPrefix(filepath.Base(path), PluginPrefix)
+			if name == "" || seen[name] || !isExecutable(path) {
+				continue
+			}
+			seen[name] = true
+			plugins = append(plugins, &PluginManager{name: name, path: path})
+		}
+	}
+	return plugins
+}
+
+func isExecutable(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.Mode().IsRegular() && fi.Mode().Perm()&0111 != 0
+}
+
+func (p *PluginManager) Name() string {
+	return p.name
+}
+
+// capabilities asks the plugin what it supports, once
+func (p *PluginManager) capabilities() (pluginResponse, error) {
+	p.once.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		p.caps, p.err = p.call(ctx, pluginRequest{Method: "capabilities"})
+		if p.err == nil && p.caps.Protocol != PluginProtocol {
+			p.err = fmt.Errorf("%s speaks plugin protocol %d, boxy speaks %d", p.path, p.caps.Protocol, PluginProtocol)
+		}
+	})
+	return p.caps, p.err
+}
+
+// supports reports whether the plugin answers method
+func (p *PluginManager) supports(method string) bool {
+	caps, err := p.capabilities()
+	if err != nil {
+		return false
+	}
+	for _, m := range caps.Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// call runs the plugin with one request and decodes its response
+func (p *PluginManager) call(ctx context.Context, req pluginRequest) (pluginResponse, error) {
+	req.Protocol = PluginProtocol
+	input, err := json.Marshal(req)
+	if err != nil {
+		return pluginResponse{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pluginTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, p.path)
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return pluginResponse{}, fmt.Errorf("%s %s: %s", p.name, req.Method, msg)
+		}
+		return pluginResponse{}, fmt.Errorf("%s %s: %w", p.name, req.Method, err)
+	}
+
+	var resp pluginResponse
+	if err := json.Unmarshal(output, &resp); err != nil {
+		return pluginResponse{}, fmt.Errorf("%s %s: bad response: %w", p.name, req.Method, err)
+	}
+	if resp.Error != "" {
+		return pluginResponse{}, fmt.Errorf("%s: %s", p.name, resp.Error)
+	}
+	return resp, nil
+}
+
+// query calls a method the plugin may not support
+func (p *PluginManager) query(ctx context.Context, req pluginRequest) (pluginResponse, error) {
+	if !p.supports(req.Method) {
+		return pluginResponse{}, fmt.Errorf("%s doesn't support %s", p.name, req.Method)
+	}
+	return p.call(ctx, req)
+}
+
+func (p *PluginManager) packages(ctx context.Context, req pluginRequest) ([]PackageInfo, error) {
+	resp, err := p.query(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]PackageInfo, len(resp.Packages))
+	for i, pkg := range resp.Packages {
+		infos[i] = pkg.info()
+	}
+	return infos, nil
+}
+
+// IsAvailable reports whether the plugin answers its capabilities request
+// with a protocol boxy speaks
+func (p *PluginManager) IsAvailable() bool {
+	_, err := p.capabilities()
+	return err == nil
+}
+
+func (p *PluginManager) Search(ctx context.Context, query string) ([]PackageInfo, error) {
+	return p.packages(ctx, pluginRequest{Method: "search", Query: query})
+}
+
+func (p *PluginManager) ListInstalled(ctx context.Context) ([]PackageInfo, error) {
+	return p.packages(ctx, pluginRequest{Method: "list"})
+}
+
+// ListManuallyInstalled falls back to everything installed for plugins
+// that don't track why packages were installed
+func (p *PluginManager) ListManuallyInstalled(ctx context.Context) ([]PackageInfo, error) {
+	if !p.supports("list_manual") {
+		return p.ListInstalled(ctx)
+	}
+	return p.packages(ctx, pluginRequest{Method: "list_manual"})
+}
+
+func (p *PluginManager) IsInstalled(ctx context.Context, pkg string) (bool, error) {
+	installed, err := p.ListInstalled(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, info := range installed {
+		if info.Name == pkg {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetInfo asks the plugin for details, or falls back to the package's
+// entry in the installed list
+func (p *PluginManager) GetInfo(ctx context.Context, pkg string) (PackageInfo, error) {
+	if !p.supports("info") {
+		installed, err := p.ListInstalled(ctx)
+		if err != nil {
+			return PackageInfo{}, err
+		}
+		for _, info := range installed {
+			if info.Name == pkg {
+				return info, nil
+			}
+		}
+		return PackageInfo{}, fmt.Errorf("%s: no information about %s", p.name, pkg)
+	}
+	resp, err := p.call(ctx, pluginRequest{Method: "info", Package: pkg})
+	if err != nil {
+		return PackageInfo{}, err
+	}
+	return resp.Package.info(), nil
+}
+
+func (p *PluginManager) Files(ctx context.Context, pkg string) ([]string, error) {
+	resp, err := p.query(ctx, pluginRequest{Method: "files", Package: pkg})
+	return resp.Files, err
+}
+
+func (p *PluginManager) Owner(ctx context.Context, path string) (string, error) {
+	resp, err := p.query(ctx, pluginRequest{Method: "owner", Path: path})
+	if err == nil && resp.Owner == "" {
+		err = fmt.Errorf("no %s package owns %s", p.name, path)
+	}
+	return resp.Owner, err
+}
+
+// Command asks the plugin for the command line of an action. If it can't
+// give one, or one that needs sudo doesn't start with it, the returned
+// command fails to start with the reason.
+func (p *PluginManager) Command(ctx context.Context, action string, pkgs ...string) *exec.Cmd {
+	resp, err := p.query(ctx, pluginRequest{Method: "command", Action: action, Packages: pkgs})
+	if err == nil && len(resp.Argv) == 0 {
+		err = fmt.Errorf("%s can't %s", p.name, action)
+	}
+	if err == nil && p.NeedsSudo() && resp.Argv[0] != "sudo" {
+		err = fmt.Errorf("%s needs sudo but gave a command that doesn't start with it: %s", p.name, strings.Join(resp.Argv, " "))
+	}
+	if err != nil {
+		cmd := exec.CommandContext(ctx, p.path)
+		cmd.Args = append([]string{p.path, action}, pkgs...)
+		cmd.Err = err
+		return cmd
+	}
+	return exec.CommandContext(ctx, resp.Argv[0], resp.Argv[1:]...)
+}
+
+func (p *PluginManager) Install(ctx context.Context, packages ...string) error {
+	return p.Command(ctx, "install", packages...).Run()
+}
+
+func (p *PluginManager) Uninstall(ctx context.Context, packages ...string) error {
+	return p.Command(ctx, "uninstall", packages...).Run()
+}
+
+// NeedsSudo reports what the plugin declared. Plugins that need it return
+// commands starting with sudo.
+func (p *PluginManager) NeedsSudo() bool {
+	caps, _ := p.capabilities()
+	return caps.NeedsSudo
+}
+
+// StatePaths lets the cache notice changes, for plugins that name the
+// files their installs touch
+func (p *PluginManager) StatePaths() []string {
+	caps, _ := p.capabilities()
+	return caps.StatePaths
+}
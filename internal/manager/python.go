@@ -0,0 +1,373 @@
+package manager
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PythonToolManager manages Python command-line tools installed into
+// their own environments under the user's home, by pipx or `uv tool`. The
+// installed list comes from the tool; details come from PyPI, which both
+// install from.
+type PythonToolManager struct {
+	userTool
+
+	name    string
+	list    func(ctx context.Context) ([]pythonApp, error)
+	command func(ctx context.Context, action string, pkgs []string) *exec.Cmd
+	toolDir func() string
+
+	dirOnce sync.Once
+	dir     string
+}
+
+// NewPipxManager returns the manager for tools installed with pipx
+func NewPipxManager() *PythonToolManager {
+	t := &PythonToolManager{name: "pipx", list: pipxList, command: pipxCommand, toolDir: pipxVenvs}
+	t.self = t
+	return t
+}
+
+// NewUvManager returns the manager for tools installed with `uv tool`
+func NewUvManager() *PythonToolManager {
+	t := &PythonToolManager{name: "uv", list: uvList, command: uvCommand, toolDir: uvToolDir}
+	t.self = t
+	return t
+}
+
+// pythonApp is one installed tool and the commands it put on PATH
+type pythonApp struct {
+	name    string
+	version string
+	bins    []string
+}
+
+func (t *PythonToolManager) Name() string {
+	return t.name
+}
+
+func (t *PythonToolManager) IsAvailable() bool {
+	_, err := exec.LookPath(t.name)
+	return err == nil
+}
+
+// StatePaths returns the tool's directory of environments, which gains or
+// loses an entry whenever a tool is installed or removed
+func (t *PythonToolManager) StatePaths() []string {
+	t.dirOnce.Do(func() { t.dir = t.toolDir() })
+	if t.dir == "" {
+		return nil
+	}
+	return []string{t.dir}
+}
+
+func (t *PythonToolManager) ListInstalled(ctx context.Context) ([]PackageInfo, error) {
+	apps, err := t.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]PackageInfo, len(apps))
+	for i, app := range apps {
+		infos[i] = PackageInfo{Name: app.name, Version: app.version, InstalledVersion: app.version, Installed: true}
+	}
+	return infos, nil
+}
+
+func (t *PythonToolManager) find(ctx context.Context, pkg string) (pythonApp, bool, error) {
+	apps, err := t.list(ctx)
+	if err != nil {
+		return pythonApp{}, false, err
+	}
+	for _, app := range apps {
+		if app.name == pkg {
+			return app, true, nil
+		}
+	}
+	return pythonApp{}, false, nil
+}
+
+func (t *PythonToolManager) IsInstalled(ctx context.Context, pkg string) (bool, error) {
+	_, ok, err := t.find(ctx, pkg)
+	return ok, err
+}
+
+// Search looks the query up as a project name on PyPI, which has no search
+// API, so only exact names are found
+func (t *PythonToolManager) Search(ctx context.Context, query string) ([]PackageInfo, error) {
+	query = strings.TrimSpace(query)
+	if query == "" || strings.ContainsAny(query, " /") {
+		return nil, nil
+	}
+	info, found, err := pypiInfo(ctx, query)
+	if err != nil || !found {
+		return nil, err
+	}
+	return []PackageInfo{info}, nil
+}
+
+// GetInfo combines the installed version with the project's details from
+// PyPI, when it can be reached
+func (t *PythonToolManager) GetInfo(ctx context.Context, pkg string) (PackageInfo, error) {
+	app, installed, err := t.find(ctx, pkg)
+	if err != nil {
+		return PackageInfo{}, err
+	}
+	info, found, err := pypiInfo(ctx, pkg)
+	if !found {
+		if !installed {
+			if err == nil {
+				err = fmt.Errorf("no PyPI project named %s", pkg)
+			}
+			return PackageInfo{}, err
+		}
+		info = PackageInfo{Name: pkg}
+	}
+	if installed {
+		info.Installed = true
+		info.InstalledVersion = app.version
+		info.Version = app.version
+	}
+	return info, nil
+}
+
+// Files lists the commands the tool put on PATH
+func (t *PythonToolManager) Files(ctx context.Context, pkg string) ([]string, error) {
+	app, ok, err := t.find(ctx, pkg)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s is not installed with %s", pkg, t.name)
+	}
+	return app.bins, nil
+}
+
+// Owner finds the tool that put path on PATH
+func (t *PythonToolManager) Owner(ctx context.Context, path string) (string, error) {
+	apps, err := t.list(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, app := range apps {
+		for _, bin := range app.bins {
+			if sameFile(bin, path) {
+				return app.name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no %s tool owns %s", t.name, path)
+}
+
+func (t *PythonToolManager) Command(ctx context.Context, action string, pkgs ...string) *exec.Cmd {
+	return t.command(ctx, action, pkgs)
+}
+
+// pinned is the requirement for one version, as both tools take it
+func (t *PythonToolManager) pinned(name, version string) string {
+	return name + "==" + version
+}
+
+// pypiInfo fetches a project's details from PyPI's JSON API. found is
+// false if there is no such project.
+func pypiInfo(ctx context.Context, name string) (info PackageInfo, found bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://pypi.org/pypi/"+url.PathEscape(name)+"/json", nil)
+	if err != nil {
+		return PackageInfo{}, false, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return PackageInfo{}, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return PackageInfo{}, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return PackageInfo{}, false, fmt.Errorf("PyPI: %s", resp.Status)
+	}
+
+	var project struct {
+		Info struct {
+			Name         string            `json:"name"`
+			Version      string            `json:"version"`
+			Summary      string            `json:"summary"`
+			HomePage     string            `json:"home_page"`
+			ProjectURLs  map[string]string `json:"project_urls"`
+			License      string            `json:"license"`
+			Author       string            `json:"author"`
+			RequiresDist []string          `json:"requires_dist"`
+		} `json:"info"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&project); err != nil {
+		return PackageInfo{}, false, fmt.Errorf("PyPI: %w", err)
+	}
+	p := project.Info
+	homepage := p.HomePage
+	if homepage == "" {
+		homepage = p.ProjectURLs["Homepage"]
+	}
+	if len(p.License) > 80 {
+		// Some projects paste the whole license text
+		p.License = ""
+	}
+	var depends []string
+	for _, req := range p.RequiresDist {
+		if !strings.Contains(req, "extra ==") {
+			depends = append(depends, req)
+		}
+	}
+	return PackageInfo{
+		Name:             p.Name,
+		Version:          p.Version,
+		Description:      p.Summary,
+		Homepage:         homepage,
+		License:          p.License,
+		Maintainer:       p.Author,
+		Depends:          depends,
+		CandidateVersion: p.Version,
+	}, true, nil
+}
+
+func pipxCommand(ctx context.Context, action string, pkgs []string) *exec.Cmd {
+	switch action {
+	case "install":
+		args := []string{"install"}
+		for _, pkg := range pkgs {
+			if strings.Contains(pkg, "==") {
+				// pipx leaves an installed tool as it is unless forced,
+				// whatever version is asked for
+				args = append(args, "--force")
+				break
+			}
+		}
+		return exec.CommandContext(ctx, "pipx", append(args, pkgs...)...)
+	case "uninstall", "upgrade":
+		return perPackage(ctx, []string{"pipx", action}, pkgs)
+	}
+	return unsupported(ctx, "pipx", action)
+}
+
+func pipxList(ctx context.Context) ([]pythonApp, error) {
+	output, err := exec.CommandContext(ctx, "pipx", "list", "--json").Output()
+	if err != nil {
+		return nil, err
+	}
+	return parsePipxList(output)
+}
+
+// parsePipxList reads `pipx list --json`: one venv per tool, with the main
+// package's version and the commands it exposes
+func parsePipxList(output []byte) ([]pythonApp, error) {
+	var list struct {
+		Venvs map[string]struct {
+			Metadata struct {
+				MainPackage struct {
+					Package        string `json:"package"`
+					PackageVersion string `json:"package_version"`
+					AppPaths       []struct {
+						Path string `json:"__Path__"`
+					} `json:"app_paths"`
+				} `json:"main_package"`
+			} `json:"metadata"`
+		} `json:"venvs"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return nil, fmt.Errorf("reading pipx list: %w", err)
+	}
+	var apps []pythonApp
+	for name, venv := range list.Venvs {
+		main := venv.Metadata.MainPackage
+		app := pythonApp{name: name, version: main.PackageVersion}
+		if main.Package != "" {
+			app.name = main.Package
+		}
+		for _, p := range main.AppPaths {
+			app.bins = append(app.bins, p.Path)
+		}
+		apps = append(apps, app)
+	}
+	sort.Slice(apps, func(i, j int) bool { return apps[i].name < apps[j].name })
+	return apps, nil
+}
+
+// pipxVenvs asks pipx where it keeps its virtualenvs, which moved between
+// versions
+func pipxVenvs() string {
+	output, err := exec.Command("pipx", "environment", "--value", "PIPX_LOCAL_VENVS").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func uvCommand(ctx context.Context, action string, pkgs []string) *exec.Cmd {
+	switch action {
+	case "install":
+		return perPackage(ctx, []string{"uv", "tool", "install"}, pkgs)
+	case "uninstall", "upgrade":
+		return exec.CommandContext(ctx, "uv", append([]string{"tool", action}, pkgs...)...)
+	case "cleanup":
+		return exec.CommandContext(ctx, "uv", "cache", "prune")
+	}
+	return unsupported(ctx, "uv", action)
+}
+
+func uvList(ctx context.Context) ([]pythonApp, error) {
+	output, err := exec.CommandContext(ctx, "uv", "tool", "list", "--show-paths").Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseUvList(output)
+}
+
+// parseUvList reads `uv tool list --show-paths`, which prints each tool as
+// "name vVERSION (dir)" followed by its commands as "- cmd (path)"
+func parseUvList(output []byte) ([]pythonApp, error) {
+	var apps []pythonApp
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if bin, ok := strings.CutPrefix(line, "- "); ok {
+			if len(apps) > 0 {
+				apps[len(apps)-1].bins = append(apps[len(apps)-1].bins, parenthesized(bin))
+			}
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.HasPrefix(fields[1], "v") {
+			continue
+		}
+		apps = append(apps, pythonApp{name: fields[0], version: strings.TrimPrefix(fields[1], "v")})
+	}
+	return apps, scanner.Err()
+}
+
+// parenthesized returns the text in the last parentheses of s, or s if it
+// has none
+func parenthesized(s string) string {
+	start := strings.LastIndex(s, "(")
+	end := strings.LastIndex(s, ")")
+	if start < 0 || end < start {
+		return s
+	}
+	return s[start+1 : end]
+}
+
+func uvToolDir() string {
+	output, err := exec.Command("uv", "tool", "dir").Output()
+	if err != nil {
+		return filepath.Join(os.Getenv("HOME"), ".local", "share", "uv", "tools")
+	}
+	return strings.TrimSpace(string(output))
+}
@@ -0,0 +1,136 @@
+package manager
+
+import (
+	"reflect"
+	"testing"
+)
+
+// uvToolList is `uv tool list --show-paths`, including the warning uv
+// prints for a tool whose environment is broken
+const uvToolList = `black v24.2.0 (/home/alice/.local/share/uv/tools/black)
+- black (/home/alice/.local/bin/black)
+- blackd (/home/alice/.local/bin/blackd)
+ruff v0.3.0 (/home/alice/.local/share/uv/tools/ruff)
+- ruff (/home/alice/.local/bin/ruff)
+warning: Ignoring malformed tool ` + "`broken`" + ` (run ` + "`uv tool uninstall broken`" + ` to remove)
+`
+
+func TestParseUvList(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []pythonApp
+	}{
+		{
+			name:   "tools and their commands",
+			output: uvToolList,
+			want: []pythonApp{
+				{name: "black", version: "24.2.0", bins: []string{"/home/alice/.local/bin/black", "/home/alice/.local/bin/blackd"}},
+				{name: "ruff", version: "0.3.0", bins: []string{"/home/alice/.local/bin/ruff"}},
+			},
+		},
+		{
+			name:   "without paths",
+			output: "httpie v3.2.2\n- http\n- https\n",
+			want:   []pythonApp{{name: "httpie", version: "3.2.2", bins: []string{"http", "https"}}},
+		},
+		{
+			name:   "nothing installed",
+			output: "No tools installed\n",
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseUvList([]byte(tt.output))
+			if err != nil {
+				t.Fatalf("parseUvList: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseUvList = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// pipxListJSON is `pipx list --json` for two tools, trimmed to the fields
+// boxy reads and a few of the rest
+const pipxListJSON = `{
+    "pipx_spec_version": "0.1",
+    "venvs": {
+        "poetry": {
+            "metadata": {
+                "injected_packages": {},
+                "main_package": {
+                    "app_paths": [{"__Path__": "/home/alice/.local/pipx/venvs/poetry/bin/poetry", "__type__": "Path"}],
+                    "apps": ["poetry"],
+                    "package": "poetry",
+                    "package_or_url": "poetry",
+                    "package_version": "1.8.2"
+                },
+                "python_version": "Python 3.11.2"
+            }
+        },
+        "httpie": {
+            "metadata": {
+                "main_package": {
+                    "app_paths": [
+                        {"__Path__": "/home/alice/.local/pipx/venvs/httpie/bin/http", "__type__": "Path"},
+                        {"__Path__": "/home/alice/.local/pipx/venvs/httpie/bin/https", "__type__": "Path"}
+                    ],
+                    "package": "httpie",
+                    "package_version": "3.2.2"
+                }
+            }
+        },
+        "my-fork": {
+            "metadata": {
+                "main_package": {
+                    "app_paths": [],
+                    "package": "",
+                    "package_version": "0.1.dev0"
+                }
+            }
+        }
+    }
+}`
+
+func TestParsePipxList(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    []pythonApp
+		wantErr bool
+	}{
+		{
+			name:   "venvs, named by their main package",
+			output: pipxListJSON,
+			want: []pythonApp{
+				{name: "httpie", version: "3.2.2", bins: []string{"/home/alice/.local/pipx/venvs/httpie/bin/http", "/home/alice/.local/pipx/venvs/httpie/bin/https"}},
+				{name: "my-fork", version: "0.1.dev0"},
+				{name: "poetry", version: "1.8.2", bins: []string{"/home/alice/.local/pipx/venvs/poetry/bin/poetry"}},
+			},
+		},
+		{
+			name:   "nothing installed",
+			output: `{"pipx_spec_version": "0.1", "venvs": {}}`,
+			want:   nil,
+		},
+		{
+			name:    "not JSON",
+			output:  "nothing has been installed with pipx 😴\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePipxList([]byte(tt.output))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePipxList error = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePipxList = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
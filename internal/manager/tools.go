@@ -0,0 +1,103 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// userLevel is implemented by managers that install into the user's home
+// rather than the system. Their packages are listed alongside the system
+// manager's instead of on their own.
+type userLevel interface {
+	userLevel() bool
+}
+
+// IsUserLevel reports whether mgr installs tools for the user only, like
+// pipx, npm -g and go install.
+func IsUserLevel(mgr PackageManager) bool {
+	if cached, ok := mgr.(*CachedManager); ok {
+		mgr = cached.PackageManager
+	}
+	u, ok := mgr.(userLevel)
+	return ok && u.userLevel()
+}
+
+// userTool is embedded by the user-level managers for what they have in
+// common: they never need sudo, everything they installed was asked for,
+// and Install and Uninstall just run Command. It calls back into the
+// manager embedding it through self, which the manager's constructor sets.
+type userTool struct {
+	self interface {
+		Command(ctx context.Context, action string, pkgs ...string) *exec.Cmd
+		ListInstalled(ctx context.Context) ([]PackageInfo, error)
+	}
+}
+
+func (u userTool) NeedsSudo() bool {
+	return false
+}
+
+func (u userTool) userLevel() bool {
+	return true
+}
+
+// ListManuallyInstalled is everything: these tools only install what
+// they're asked for, with no dependencies of their own in the list
+func (u userTool) ListManuallyInstalled(ctx context.Context) ([]PackageInfo, error) {
+	return u.self.ListInstalled(ctx)
+}
+
+func (u userTool) Install(ctx context.Context, packages ...string) error {
+	return u.self.Command(ctx, "install", packages...).Run()
+}
+
+func (u userTool) Uninstall(ctx context.Context, packages ...string) error {
+	return u.self.Command(ctx, "uninstall", packages...).Run()
+}
+
+// perPackage runs argv once per package in a shell, stopping at the first
+// failure, for tools that take one package per command
+func perPackage(ctx context.Context, argv []string, pkgs []string) *exec.Cmd {
+	if len(pkgs) == 1 {
+		return exec.CommandContext(ctx, argv[0], append(argv[1:], pkgs...)...)
+	}
+	script := fmt.Sprintf(`set -e; for p; do %s "$p"; done`, strings.Join(argv, " "))
+	return exec.CommandContext(ctx, "sh", append([]string{"-c", script, "sh"}, pkgs...)...)
+}
+
+// unsupported returns a command that fails to start with err
+func unsupported(ctx context.Context, name, action string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, action)
+	cmd.Err = fmt.Errorf("%s can't %s", name, action)
+	return cmd
+}
+
+// searchCapable is implemented by managers that may have nowhere to
+// search, like go install, which has no package registry to ask
+type searchCapable interface {
+	canSearch() bool
+}
+
+// CanSearch reports whether mgr's Search can find packages. Managers that
+// can't are left out of searches rather than failing them.
+func CanSearch(mgr PackageManager) bool {
+	if cached, ok := mgr.(*CachedManager); ok {
+		mgr = cached.PackageManager
+	}
+	s, ok := mgr.(searchCapable)
+	return !ok || s.canSearch()
+}
+
+// sameFile reports whether a and b are the same file, following symlinks,
+// or the same path if either is missing
+func sameFile(a, b string) bool {
+	fa, errA := os.Stat(a)
+	fb, errB := os.Stat(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return os.SameFile(fa, fb)
+}
@@ -0,0 +1,96 @@
+package manager
+
+import (
+	"strconv"
+	"strings"
+)
+
+// compareDebVersions orders two Debian version strings
+// ([epoch:]upstream[-revision]) following dpkg's algorithm. It returns
+// a negative number, zero, or a positive number like strings.Compare.
+func compareDebVersions(a, b string) int {
+	epochA, restA := splitEpoch(a)
+	epochB, restB := splitEpoch(b)
+	if epochA != epochB {
+		if epochA < epochB {
+			return -1
+		}
+		return 1
+	}
+
+	upA, revA := splitRevision(restA)
+	upB, revB := splitRevision(restB)
+	if c := compareDebFragment(upA, upB); c != 0 {
+		return c
+	}
+	return compareDebFragment(revA, revB)
+}
+
+func splitEpoch(v string) (int, string) {
+	if before, after, ok := strings.Cut(v, ":"); ok {
+		if epoch, err := strconv.Atoi(before); err == nil {
+			return epoch, after
+		}
+	}
+	return 0, v
+}
+
+func splitRevision(v string) (string, string) {
+	if i := strings.LastIndex(v, "-"); i >= 0 {
+		return v[:i], v[i+1:]
+	}
+	return v, ""
+}
+
+// compareDebFragment compares alternating non-digit and digit runs. In the
+// non-digit runs letters sort before non-letters and "~" sorts before
+// everything, even the end of the string.
+func compareDebFragment(a, b string) int {
+	for a != "" || b != "" {
+		var textA, textB string
+		textA, a = splitRun(a, false)
+		textB, b = splitRun(b, false)
+		for i := 0; i < len(textA) || i < len(textB); i++ {
+			if c := debCharOrder(textA, i) - debCharOrder(textB, i); c != 0 {
+				return c
+			}
+		}
+
+		var numA, numB string
+		numA, a = splitRun(a, true)
+		numB, b = splitRun(b, true)
+		numA = strings.TrimLeft(numA, "0")
+		numB = strings.TrimLeft(numB, "0")
+		if len(numA) != len(numB) {
+			return len(numA) - len(numB)
+		}
+		if c := strings.Compare(numA, numB); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// splitRun splits off the leading run of digits (or non-digits).
+func splitRun(s string, digits bool) (string, string) {
+	i := 0
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9') == digits {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func debCharOrder(s string, i int) int {
+	if i >= len(s) {
+		return 0
+	}
+	c := s[i]
+	switch {
+	case c == '~':
+		return -1
+	case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z':
+		return int(c)
+	default:
+		return int(c) + 256
+	}
+}
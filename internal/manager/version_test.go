@@ -0,0 +1,49 @@
+package manager
+
+import "testing"
+
+func TestCompareDebVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int // the sign of the result
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "1.1", -1},
+		{"1.10", "1.9", 1},
+		{"1.01", "1.1", 0},
+		{"1:0.9", "2.0", 1},
+		{"2.0", "1:0.9", -1},
+		{"1.0-1", "1.0-2", -1},
+		{"1.0-10", "1.0-9", 1},
+		{"7.88.1-10+deb12u5", "7.88.1-10+deb12u4", 1},
+		{"7.88.1-10+deb12u5", "7.88.1-10", 1},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0~rc1", "1.0~rc2", -1},
+		{"1.0~~", "1.0~", -1},
+		{"1.0a", "1.0", 1},
+		{"1.0a", "1.0+", -1},
+		{"1.0.0", "1.0", 1},
+		{"2.36-9+deb12u4", "2.36-9+deb12u10", -1},
+		{"1:2.39.2-1.1", "1:2.39.2-1", 1},
+		{"0.18.0", "0.9.3", 1}, // crates' semver
+	}
+	for _, tt := range tests {
+		got := compareDebVersions(tt.a, tt.b)
+		if sign(got) != tt.want {
+			t.Errorf("compareDebVersions(%q, %q) = %d, want sign %d", tt.a, tt.b, got, tt.want)
+		}
+		if back := compareDebVersions(tt.b, tt.a); sign(back) != -tt.want {
+			t.Errorf("compareDebVersions(%q, %q) = %d, want sign %d", tt.b, tt.a, back, -tt.want)
+		}
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
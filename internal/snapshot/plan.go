@@ -0,0 +1,340 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"boxy/internal/config"
+	"boxy/internal/history"
+	"boxy/internal/manager"
+)
+
+// Plan is what restoring a snapshot would change
+type Plan struct {
+	Snapshot *Snapshot
+	Managers []ManagerPlan
+	// Missing names the snapshot's managers this machine doesn't have
+	Missing []string
+	// Bookmarks is set when the bookmarks differ from the snapshot's
+	Bookmarks bool
+}
+
+// ManagerPlan is the restore for one manager. Changes run from the current
+// version to the snapshot's: an empty After is a package to remove, an
+// empty Before one to install.
+type ManagerPlan struct {
+	Manager manager.PackageManager
+	Changes []history.Change
+	// Unpinned are version changes the manager can't make, as it only
+	// installs the current version
+	Unpinned []history.Change
+	Hold     []string
+	Unhold   []string
+	// Manual and Auto are packages to mark as installed on request or as
+	// dependencies, so a later autoremove takes the same ones
+	Manual []string
+	Auto   []string
+}
+
+// Op is one command of a restore
+type Op struct {
+	Manager manager.PackageManager
+	Action  string   // install, uninstall, manual, auto, hold or unhold
+	Pkgs    []string // with pinned versions, for installs
+	Names   []string // Pkgs without the versions
+	// Fallback is run if a pinned install fails, for when the snapshot's
+	// versions are no longer available: the packages that aren't
+	// installed at all, at the current version. Installing one that is
+	// would leave it as it is.
+	Fallback []string
+	// Changed are the install's version changes, which have nothing to
+	// fall back to
+	Changed []history.Change
+}
+
+// FallbackError is what's left of the op's err once its fallback has put
+// back the missing packages: the versions still to restore, if any
+func (op Op) FallbackError(err error) error {
+	if len(op.Changed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("couldn't restore %s: %w", versions(op.Changed), err)
+}
+
+// versions lists changes by the snapshot's version
+func versions(changes []history.Change) string {
+	list := make([]string, len(changes))
+	for i, c := range changes {
+		list[i] = c.Name + " " + c.After
+	}
+	return strings.Join(list, ", ")
+}
+
+// Compare works out what restoring s would change, given the managers on
+// this machine and the current bookmarks.
+func Compare(ctx context.Context, s *Snapshot, managers []manager.PackageManager, cfg *config.Config) (Plan, error) {
+	plan := Plan{Snapshot: s}
+	byName := make(map[string]manager.PackageManager, len(managers))
+	for _, mgr := range managers {
+		byName[mgr.Name()] = mgr
+	}
+
+	names := make([]string, 0, len(s.Managers))
+	for name := range s.Managers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		mgr, ok := byName[name]
+		if !ok {
+			plan.Missing = append(plan.Missing, name)
+			continue
+		}
+		state := s.Managers[name]
+		installed, err := mgr.ListInstalled(ctx)
+		if err != nil {
+			return Plan{}, fmt.Errorf("listing %s packages: %w", name, err)
+		}
+		holds, err := manager.Holds(ctx, mgr)
+		if err != nil {
+			return Plan{}, fmt.Errorf("listing %s holds: %w", name, err)
+		}
+
+		want := make([]manager.PackageInfo, len(state.Packages))
+		for i, pkg := range state.Packages {
+			want[i] = manager.PackageInfo{Name: pkg.Name, Version: pkg.Version}
+		}
+		mp := ManagerPlan{
+			Manager: mgr,
+			Hold:    missing(state.Holds, holds),
+			Unhold:  missing(holds, state.Holds),
+		}
+		pins := manager.CanPin(mgr)
+		for _, c := range manager.Diff(installed, want) {
+			if !pins && c.Before != "" && c.After != "" {
+				mp.Unpinned = append(mp.Unpinned, c)
+			} else {
+				mp.Changes = append(mp.Changes, c)
+			}
+		}
+		sort.Slice(mp.Changes, func(i, j int) bool { return mp.Changes[i].Name < mp.Changes[j].Name })
+		sort.Slice(mp.Unpinned, func(i, j int) bool { return mp.Unpinned[i].Name < mp.Unpinned[j].Name })
+		if state.Manual != nil && manager.MarksManual(mgr) {
+			manual, err := mgr.ListManuallyInstalled(ctx)
+			if err != nil {
+				return Plan{}, fmt.Errorf("listing manually installed %s packages: %w", name, err)
+			}
+			mp.Manual, mp.Auto = remark(state, manual, mp.Changes)
+		}
+		if len(mp.Changes) > 0 || len(mp.Unpinned) > 0 || len(mp.Hold) > 0 || len(mp.Unhold) > 0 || len(mp.Manual) > 0 || len(mp.Auto) > 0 {
+			plan.Managers = append(plan.Managers, mp)
+		}
+	}
+
+	plan.Bookmarks = !reflect.DeepEqual(normalize(cfg.Packages), normalize(s.Bookmarks))
+	return plan, nil
+}
+
+// remark works out which of the snapshot's packages need marking manual
+// or auto once restored, given those marked manual now. Packages the
+// restore installs or changes the version of end up manual, as they're
+// installed by name.
+func remark(state ManagerState, manual []manager.PackageInfo, changes []history.Change) (toManual, toAuto []string) {
+	isManual := make(map[string]bool, len(manual))
+	for _, pkg := range manual {
+		isManual[pkg.Name] = true
+	}
+	for _, c := range changes {
+		if c.After != "" {
+			isManual[c.Name] = true
+		}
+	}
+	wantManual := make(map[string]bool, len(state.Manual))
+	for _, name := range state.Manual {
+		wantManual[name] = true
+	}
+	for _, pkg := range state.Packages {
+		switch {
+		case wantManual[pkg.Name] && !isManual[pkg.Name]:
+			toManual = append(toManual, pkg.Name)
+		case !wantManual[pkg.Name] && isManual[pkg.Name]:
+			toAuto = append(toAuto, pkg.Name)
+		}
+	}
+	sort.Strings(toManual)
+	sort.Strings(toAuto)
+	return toManual, toAuto
+}
+
+// missing returns the names in want that aren't in have
+func missing(want, have []string) []string {
+	present := make(map[string]bool, len(have))
+	for _, name := range have {
+		present[name] = true
+	}
+	var out []string
+	for _, name := range want {
+		if !present[name] {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// normalize makes empty and nil bookmark lists compare equal
+func normalize(pkgs []config.Package) []config.Package {
+	if len(pkgs) == 0 {
+		return nil
+	}
+	return pkgs
+}
+
+// Empty reports whether the machine already matches the snapshot
+func (p Plan) Empty() bool {
+	return len(p.Managers) == 0 && !p.Bookmarks
+}
+
+// UnpinnedError reports the version changes the restore can't make, for
+// after it has made the rest, or nil if there are none
+func (p Plan) UnpinnedError() error {
+	var parts []string
+	for _, mp := range p.Managers {
+		if len(mp.Unpinned) > 0 {
+			parts = append(parts, fmt.Sprintf("%s can't install %s", mp.Manager.Name(), versions(mp.Unpinned)))
+		}
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	return fmt.Errorf("versions left as they are: %s", strings.Join(parts, "; "))
+}
+
+// Ops returns the commands that carry out the plan: removals first, then
+// installs at the snapshot's versions, then manual and auto marks, then
+// holds, which need the right version in place. Unpinned changes are left
+// out.
+func (p Plan) Ops() []Op {
+	var ops []Op
+	named := func(mgr manager.PackageManager, action string, pkgs []string) Op {
+		return Op{Manager: mgr, Action: action, Pkgs: pkgs, Names: pkgs}
+	}
+	for _, mp := range p.Managers {
+		var remove []string
+		install := Op{Manager: mp.Manager, Action: "install"}
+		pins := manager.CanPin(mp.Manager)
+		for _, c := range mp.Changes {
+			switch {
+			case c.After == "":
+				remove = append(remove, c.Name)
+				continue
+			case c.Before == "" && pins:
+				install.Fallback = append(install.Fallback, c.Name)
+			case c.Before != "":
+				install.Changed = append(install.Changed, c)
+			}
+			install.Names = append(install.Names, c.Name)
+			install.Pkgs = append(install.Pkgs, manager.Pinned(mp.Manager, c.Name, c.After))
+		}
+		if len(mp.Unhold) > 0 {
+			ops = append(ops, named(mp.Manager, "unhold", mp.Unhold))
+		}
+		if len(remove) > 0 {
+			ops = append(ops, named(mp.Manager, "uninstall", remove))
+		}
+		if len(install.Pkgs) > 0 {
+			ops = append(ops, install)
+		}
+		if len(mp.Manual) > 0 {
+			ops = append(ops, named(mp.Manager, "manual", mp.Manual))
+		}
+		if len(mp.Auto) > 0 {
+			ops = append(ops, named(mp.Manager, "auto", mp.Auto))
+		}
+		if len(mp.Hold) > 0 {
+			ops = append(ops, named(mp.Manager, "hold", mp.Hold))
+		}
+	}
+	return ops
+}
+
+// NeedsSudo reports whether any of the plan's commands run under sudo
+func (p Plan) NeedsSudo() bool {
+	for _, mp := range p.Managers {
+		if mp.Manager.NeedsSudo() {
+			return true
+		}
+	}
+	return false
+}
+
+// Summary counts the plan's changes, for confirming a restore
+func (p Plan) Summary() string {
+	var install, remove, change, unpinned, marks, holds int
+	for _, mp := range p.Managers {
+		for _, c := range mp.Changes {
+			switch {
+			case c.Before == "":
+				install++
+			case c.After == "":
+				remove++
+			default:
+				change++
+			}
+		}
+		unpinned += len(mp.Unpinned)
+		marks += len(mp.Manual) + len(mp.Auto)
+		holds += len(mp.Hold) + len(mp.Unhold)
+	}
+	var parts []string
+	for _, part := range []struct {
+		n    int
+		what string
+	}{{install, "install"}, {remove, "remove"}, {change, "change version of"}, {unpinned, "can't change version of"}, {marks, "mark manual or auto"}, {holds, "hold or unhold"}} {
+		if part.n > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", part.what, part.n))
+		}
+	}
+	if p.Bookmarks {
+		parts = append(parts, "replace bookmarks")
+	}
+	if len(parts) == 0 {
+		return "no changes"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Lines lists every change the plan makes, grouped by manager
+func (p Plan) Lines() []string {
+	var lines []string
+	for _, mp := range p.Managers {
+		lines = append(lines, mp.Manager.Name()+":")
+		for _, c := range mp.Changes {
+			lines = append(lines, "  "+c.String())
+		}
+		for _, c := range mp.Unpinned {
+			lines = append(lines, fmt.Sprintf("  %s (can't install a given version)", c))
+		}
+		for _, name := range mp.Manual {
+			lines = append(lines, "  mark manual "+name)
+		}
+		for _, name := range mp.Auto {
+			lines = append(lines, "  mark auto "+name)
+		}
+		for _, name := range mp.Unhold {
+			lines = append(lines, "  unhold "+name)
+		}
+		for _, name := range mp.Hold {
+			lines = append(lines, "  hold "+name)
+		}
+	}
+	if p.Bookmarks {
+		lines = append(lines, fmt.Sprintf("bookmarks: replace with the snapshot's %d", len(p.Snapshot.Bookmarks)))
+	}
+	for _, name := range p.Missing {
+		lines = append(lines, fmt.Sprintf("%s: skipped, not on this machine", name))
+	}
+	return lines
+}
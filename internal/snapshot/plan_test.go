@@ -0,0 +1,210 @@
+package snapshot
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"boxy/internal/config"
+	"boxy/internal/history"
+	"boxy/internal/manager"
+)
+
+// fakeManager lists installed and can only install the current version,
+// like brew
+type fakeManager struct {
+	manager.PackageManager
+	installed []manager.PackageInfo
+}
+
+func (f *fakeManager) Name() string { return "fake" }
+
+func (f *fakeManager) ListInstalled(ctx context.Context) ([]manager.PackageInfo, error) {
+	return f.installed, nil
+}
+
+// pinningManager lists installed and installs given versions the way pipx
+// does
+type pinningManager struct {
+	*manager.PythonToolManager
+	installed []manager.PackageInfo
+}
+
+func (p *pinningManager) Name() string { return "pinning" }
+
+func (p *pinningManager) ListInstalled(ctx context.Context) ([]manager.PackageInfo, error) {
+	return p.installed, nil
+}
+
+func TestCompare(t *testing.T) {
+	installed := []manager.PackageInfo{{Name: "jq", Version: "1.7"}, {Name: "curl", Version: "7.88"}}
+
+	tests := []struct {
+		name         string
+		mgr          manager.PackageManager
+		state        ManagerState
+		wantChanges  []history.Change
+		wantUnpinned []history.Change
+	}{
+		{
+			name:        "removed package",
+			mgr:         &pinningManager{PythonToolManager: manager.NewPipxManager(), installed: installed},
+			state:       ManagerState{Packages: []Package{{Name: "jq", Version: "1.7"}}},
+			wantChanges: []history.Change{{Name: "curl", Before: "7.88"}},
+		},
+		{
+			name:        "version changed",
+			mgr:         &pinningManager{PythonToolManager: manager.NewPipxManager(), installed: installed},
+			state:       ManagerState{Packages: []Package{{Name: "jq", Version: "1.6"}, {Name: "curl", Version: "7.88"}, {Name: "wget", Version: "1.21"}}},
+			wantChanges: []history.Change{{Name: "jq", Before: "1.7", After: "1.6"}, {Name: "wget", After: "1.21"}},
+		},
+		{
+			name:         "version changed with no pinner",
+			mgr:          &fakeManager{installed: installed},
+			state:        ManagerState{Packages: []Package{{Name: "jq", Version: "1.6"}, {Name: "curl", Version: "7.88"}, {Name: "wget", Version: "1.21"}}},
+			wantChanges:  []history.Change{{Name: "wget", After: "1.21"}},
+			wantUnpinned: []history.Change{{Name: "jq", Before: "1.7", After: "1.6"}},
+		},
+		{
+			name:  "nothing to change",
+			mgr:   &fakeManager{installed: installed},
+			state: ManagerState{Packages: []Package{{Name: "curl", Version: "7.88"}, {Name: "jq", Version: "1.7"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Snapshot{Managers: map[string]ManagerState{tt.mgr.Name(): tt.state, "apt": {}}}
+			plan, err := Compare(context.Background(), s, []manager.PackageManager{tt.mgr}, &config.Config{})
+			if err != nil {
+				t.Fatalf("Compare: %v", err)
+			}
+			if !reflect.DeepEqual(plan.Missing, []string{"apt"}) {
+				t.Errorf("Missing = %q, want [apt]", plan.Missing)
+			}
+			if tt.wantChanges == nil && tt.wantUnpinned == nil {
+				if !plan.Empty() {
+					t.Fatalf("plan = %+v, want it empty", plan.Managers)
+				}
+				return
+			}
+			if len(plan.Managers) != 1 {
+				t.Fatalf("plan has %d managers, want 1", len(plan.Managers))
+			}
+			mp := plan.Managers[0]
+			if !reflect.DeepEqual(mp.Changes, tt.wantChanges) {
+				t.Errorf("Changes = %+v, want %+v", mp.Changes, tt.wantChanges)
+			}
+			if !reflect.DeepEqual(mp.Unpinned, tt.wantUnpinned) {
+				t.Errorf("Unpinned = %+v, want %+v", mp.Unpinned, tt.wantUnpinned)
+			}
+		})
+	}
+}
+
+func TestRemark(t *testing.T) {
+	tests := []struct {
+		name       string
+		state      ManagerState
+		manual     []string
+		changes    []history.Change
+		wantManual []string
+		wantAuto   []string
+	}{
+		{
+			name:       "marks differ",
+			state:      ManagerState{Packages: []Package{{Name: "jq"}, {Name: "libjq1"}, {Name: "curl"}}, Manual: []string{"jq", "curl"}},
+			manual:     []string{"libjq1", "curl"},
+			wantManual: []string{"jq"},
+			wantAuto:   []string{"libjq1"},
+		},
+		{
+			name:     "reinstalled dependency",
+			state:    ManagerState{Packages: []Package{{Name: "jq"}, {Name: "libjq1"}}, Manual: []string{"jq"}},
+			manual:   []string{"jq"},
+			changes:  []history.Change{{Name: "libjq1", After: "1.6"}},
+			wantAuto: []string{"libjq1"},
+		},
+		{
+			name:    "version changed",
+			state:   ManagerState{Packages: []Package{{Name: "jq"}}, Manual: []string{"jq"}},
+			changes: []history.Change{{Name: "jq", Before: "1.7", After: "1.6"}},
+		},
+		{
+			name:    "removed package",
+			state:   ManagerState{Packages: []Package{{Name: "jq"}}, Manual: []string{"jq"}},
+			manual:  []string{"jq", "curl"},
+			changes: []history.Change{{Name: "curl", Before: "7.88"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manual := make([]manager.PackageInfo, len(tt.manual))
+			for i, name := range tt.manual {
+				manual[i] = manager.PackageInfo{Name: name}
+			}
+			toManual, toAuto := remark(tt.state, manual, tt.changes)
+			if !reflect.DeepEqual(toManual, tt.wantManual) {
+				t.Errorf("to manual = %q, want %q", toManual, tt.wantManual)
+			}
+			if !reflect.DeepEqual(toAuto, tt.wantAuto) {
+				t.Errorf("to auto = %q, want %q", toAuto, tt.wantAuto)
+			}
+		})
+	}
+}
+
+func TestOps(t *testing.T) {
+	pinning := &pinningManager{PythonToolManager: manager.NewPipxManager()}
+	fake := &fakeManager{}
+	downgrade := history.Change{Name: "jq", Before: "1.7", After: "1.6"}
+
+	tests := []struct {
+		name string
+		plan Plan
+		want []Op
+	}{
+		{
+			name: "empty plan",
+			plan: Plan{},
+		},
+		{
+			name: "removed and version-changed packages",
+			plan: Plan{Managers: []ManagerPlan{{
+				Manager: pinning,
+				Changes: []history.Change{{Name: "curl", Before: "7.88"}, downgrade, {Name: "wget", After: "1.21"}},
+				Hold:    []string{"jq"},
+			}}},
+			want: []Op{
+				{Manager: pinning, Action: "uninstall", Pkgs: []string{"curl"}, Names: []string{"curl"}},
+				{
+					Manager:  pinning,
+					Action:   "install",
+					Pkgs:     []string{"jq==1.6", "wget==1.21"},
+					Names:    []string{"jq", "wget"},
+					Fallback: []string{"wget"},
+					Changed:  []history.Change{downgrade},
+				},
+				{Manager: pinning, Action: "hold", Pkgs: []string{"jq"}, Names: []string{"jq"}},
+			},
+		},
+		{
+			name: "no pinner",
+			plan: Plan{Managers: []ManagerPlan{{
+				Manager:  fake,
+				Changes:  []history.Change{{Name: "wget", After: "1.21"}},
+				Unpinned: []history.Change{downgrade},
+			}}},
+			want: []Op{
+				{Manager: fake, Action: "install", Pkgs: []string{"wget"}, Names: []string{"wget"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ops := tt.plan.Ops()
+			if !reflect.DeepEqual(ops, tt.want) {
+				t.Fatalf("Ops = %+v, want %+v", ops, tt.want)
+			}
+		})
+	}
+}
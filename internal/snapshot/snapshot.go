@@ -0,0 +1,188 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"boxy/internal/config"
+	"boxy/internal/history"
+	"boxy/internal/manager"
+)
+
+// Snapshot is the state of the machine's packages at one point: what each
+// manager had installed and held, and the bookmarks.
+type Snapshot struct {
+	Name      string                  `json:"name"`
+	Created   time.Time               `json:"created"`
+	Managers  map[string]ManagerState `json:"managers"`
+	Bookmarks []config.Package        `json:"bookmarks"`
+}
+
+// ManagerState is what one manager had installed
+type ManagerState struct {
+	Packages []Package `json:"packages"`
+	Holds    []string  `json:"holds,omitempty"`
+	// Manual names the packages installed on request rather than as
+	// dependencies, for managers that tell them apart (nil otherwise)
+	Manual []string `json:"manual,omitempty"`
+}
+
+type Package struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// validName keeps snapshot names usable as file names
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Dir returns where snapshots are kept, under boxy's state dir.
+func Dir() (string, error) {
+	dir, err := history.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "snapshots"), nil
+}
+
+func path(name string) (string, error) {
+	if !validName.MatchString(name) {
+		return "", fmt.Errorf("snapshot names may only use letters, digits, '.', '_' and '-', got %q", name)
+	}
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+".json"), nil
+}
+
+// Take records what managers have installed and held now, which of their
+// packages were installed manually where that's tracked, and cfg's
+// bookmarks.
+func Take(ctx context.Context, name string, managers []manager.PackageManager, cfg *config.Config) (*Snapshot, error) {
+	s := &Snapshot{
+		Name:      name,
+		Created:   time.Now(),
+		Managers:  make(map[string]ManagerState),
+		Bookmarks: cfg.Packages,
+	}
+	for _, mgr := range managers {
+		installed, err := mgr.ListInstalled(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("listing %s packages: %w", mgr.Name(), err)
+		}
+		holds, err := manager.Holds(ctx, mgr)
+		if err != nil {
+			return nil, fmt.Errorf("listing %s holds: %w", mgr.Name(), err)
+		}
+		state := ManagerState{Holds: holds}
+		if manager.MarksManual(mgr) {
+			manual, err := mgr.ListManuallyInstalled(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("listing manually installed %s packages: %w", mgr.Name(), err)
+			}
+			for _, pkg := range manual {
+				state.Manual = append(state.Manual, pkg.Name)
+			}
+		}
+		for _, pkg := range installed {
+			state.Packages = append(state.Packages, Package{Name: pkg.Name, Version: pkg.Version})
+		}
+		s.Managers[mgr.Name()] = state
+	}
+	return s, nil
+}
+
+// Save writes s to the snapshot directory, replacing any snapshot of the
+// same name
+func (s *Snapshot) Save() error {
+	path, err := path(s.Name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func Load(name string) (*Snapshot, error) {
+	path, err := path(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no snapshot named %q", name)
+		}
+		return nil, err
+	}
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("reading snapshot %s: %w", name, err)
+	}
+	s.Name = name
+	return &s, nil
+}
+
+func Delete(name string) error {
+	path, err := path(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no snapshot named %q", name)
+		}
+		return err
+	}
+	return nil
+}
+
+// List returns the saved snapshots, newest first. Files that don't parse
+// are skipped.
+func List() ([]*Snapshot, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []*Snapshot
+	for _, file := range files {
+		if s, err := Load(strings.TrimSuffix(filepath.Base(file), ".json")); err == nil {
+			snapshots = append(snapshots, s)
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Created.After(snapshots[j].Created)
+	})
+	return snapshots, nil
+}
+
+// Summary counts what s holds, for listings
+func (s *Snapshot) Summary() string {
+	names := make([]string, 0, len(s.Managers))
+	for name := range s.Managers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	counts := make([]string, len(names))
+	for i, name := range names {
+		counts[i] = fmt.Sprintf("%s %d", name, len(s.Managers[name].Packages))
+	}
+	return fmt.Sprintf("%s, %d bookmarks", strings.Join(counts, ", "), len(s.Bookmarks))
+}
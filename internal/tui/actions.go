@@ -0,0 +1,128 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"boxy/internal/manager"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxLogLines caps the session log kept for "open log"
+const maxLogLines = 2000
+
+// refresh reloads the package lists, dropping fetched info
+func (m *Model) refresh() tea.Cmd {
+	m.refreshing = true
+	m.details = make(map[string]manager.PackageInfo)
+	m.detailPkg = ""
+	return tea.Batch(m.loadPackages(), m.followCursor())
+}
+
+func (m *Model) requestCleanup() {
+	m.confirmPkg = ""
+	m.confirmAct = confirmCleanup
+	// Built here rather than on every render, as a plugin's Command runs
+	// the plugin
+	m.cleanupCmd = strings.Join(m.mgr.Command(context.Background(), "cleanup").Args, " ")
+	m.viewMode = viewConfirm
+}
+
+// switchManager makes mgr the active manager and loads its lists
+func (m *Model) switchManager(mgr manager.PackageManager) tea.Cmd {
+	m.clearSearch()
+	m.searchFilters.source = ""
+	m.mgr = mgr
+	m.tools = userTools(m.managers, mgr)
+	m.items = nil
+	m.manualSet = nil
+	m.listChanged()
+	m.index = nil
+	m.details = make(map[string]manager.PackageInfo)
+	m.detailPkg = ""
+	m.loading = true
+	m.showCachedLists()
+	m.statusMsg = fmt.Sprintf("Switched to %s", mgr.Name())
+	m.statusErr = false
+	return tea.Batch(m.loadPackages(), m.warmIndex())
+}
+
+// exportList writes the names and versions of the packages in the list to
+// a file in the working directory
+func (m *Model) exportList() {
+	var b strings.Builder
+	items := m.visibleItems()
+	for _, item := range items {
+		b.WriteString(strings.TrimSpace(item.info.Name + " " + item.info.Version))
+		b.WriteString("\n")
+	}
+
+	path := fmt.Sprintf("boxy-%s-%s.txt", m.mgr.Name(), time.Now().Format("20060102-150405"))
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		m.statusMsg = fmt.Sprintf("Export failed: %v", err)
+		m.statusErr = true
+		return
+	}
+	m.statusMsg = fmt.Sprintf("Exported %d packages to %s", len(items), path)
+	m.statusErr = false
+}
+
+func (m *Model) appendLog(msg commandOutputMsg) {
+	m.log = append(m.log, fmt.Sprintf("[%s] $ %s", time.Now().Format("15:04:05"), msg.command))
+	if output := strings.TrimRight(msg.output, "\n"); output != "" {
+		m.log = append(m.log, strings.Split(output, "\n")...)
+	}
+	if msg.err != nil {
+		m.log = append(m.log, fmt.Sprintf("error: %v", msg.err))
+	}
+	if msg.historyErr != nil {
+		m.log = append(m.log, fmt.Sprintf("not recorded in history: %v", msg.historyErr))
+	}
+	m.log = append(m.log, "")
+	if len(m.log) > maxLogLines {
+		m.log = m.log[len(m.log)-maxLogLines:]
+	}
+}
+
+// openLog shows the session's command output in the info modal, scrolled
+// to the end
+func (m *Model) openLog() {
+	text := strings.Join(m.log, "\n")
+	if text == "" {
+		text = "No commands run yet"
+	}
+	m.openInfo("", text)
+	m.infoTitle = "Log"
+	m.scrollInfo(len(m.infoLines()))
+}
+
+// jumpTo moves the cursor to pkg, widening the list if it's filtered out
+func (m *Model) jumpTo(pkg string) {
+	find := func() bool {
+		for i, item := range m.visibleItems() {
+			if item.info.Name == pkg {
+				m.cursor = i
+				m.ensureCursorVisible()
+				return true
+			}
+		}
+		return false
+	}
+	if find() {
+		return
+	}
+	if m.filtered != nil {
+		m.searchFilters = searchFilters{}
+		if find() {
+			return
+		}
+	}
+	m.clearSearch()
+	m.viewFilter = filterAll
+	m.tagFilter = ""
+	find()
+}
@@ -6,10 +6,16 @@ import (
 	"os/exec"
 	"sort"
 	"strings"
+	"time"
 
 	"boxy/internal/config"
 	"boxy/internal/manager"
+	"boxy/internal/snapshot"
 
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -23,6 +29,11 @@ const (
 	viewInfo
 	viewConfirm
 	viewSudoPassword
+	viewHelp
+	viewPalette
+	viewTags
+	viewNote
+	viewSnapshots
 )
 
 type confirmAction int
@@ -30,8 +41,50 @@ type confirmAction int
 const (
 	confirmInstall confirmAction = iota
 	confirmUninstall
+	confirmCleanup
+	confirmInstallTag // everything with the tag in confirmPkg
+	confirmUndo
+	confirmRestore
+	confirmUpgrade
 )
 
+func (a confirmAction) verb() string {
+	switch a {
+	case confirmUninstall:
+		return "uninstall"
+	case confirmCleanup:
+		return "clean up"
+	case confirmUndo:
+		return "undo"
+	case confirmRestore:
+		return "restore"
+	case confirmUpgrade:
+		return "upgrade"
+	default:
+		return "install"
+	}
+}
+
+func (a confirmAction) progress() string {
+	switch a {
+	case confirmUninstall:
+		return "Uninstalling"
+	case confirmCleanup:
+		return "Cleaning up"
+	case confirmUndo:
+		return "Undoing"
+	case confirmRestore:
+		return "Restoring"
+	case confirmUpgrade:
+		return "Upgrading"
+	default:
+		return "Installing"
+	}
+}
+
+// searchDebounce is how long typing must pause before a remote search runs
+const searchDebounce = 300 * time.Millisecond
+
 type viewFilter int
 
 const (
@@ -43,35 +96,96 @@ const (
 type packageItem struct {
 	info       manager.PackageInfo
 	bookmarked bool
+	nameMatch  []int // fuzzy filter match positions, for highlighting
+	descMatch  []int
+	tags       []string
+	note       string
 }
 
 type Model struct {
-	mgr        manager.PackageManager
-	cfg        *config.Config
-	keys       keyMap
-	width      int
-	height     int
-	cursor     int
-	scroll     int // scroll offset for viewport
-	viewMode   viewMode
+	mgr           manager.PackageManager
+	managers      []manager.PackageManager // every detected manager, for switching
+	tools         []manager.PackageManager // user-level managers listed with mgr
+	cfg           *config.Config
+	keys          keyMap
+	help          help.Model
+	width         int
+	height        int
+	cursor        int
+	scroll        int // scroll offset for viewport
+	viewMode      viewMode
 	searchInput   textinput.Model
 	passwordInput textinput.Model
 	items         []packageItem
 	filtered      []packageItem
+	filterQuery   string // local fuzzy filter over items
+	searchRemote  bool   // search bar runs a remote search instead of filtering
+	searchFilters searchFilters
 	infoText      string
+	infoTitle     string // modal title when not "Package Info"
+	infoScroll    int
+	infoPkg       string
+	infoFiles     []string
+	filesLoaded   bool // infoFiles is nil for a package with no files
+	filesErr      error
+	showFiles     bool
 	confirmPkg    string
+	confirmPkgs   []pkgKey // packages of a tag install
 	confirmAct    confirmAction
+	confirmMgr    manager.PackageManager // runs the pending action, if not mgr
+	cleanupCmd    string                 // the cleanup command, shown when confirming it
+	undoPlan      undoPlan
+	restorePlan   snapshot.Plan
 	sudoPassword  string
 	statusMsg     string
 	statusErr     bool
 	loading       bool
+	refreshing    bool
 	searching     bool
+	searchGen     int                // bumped per query; stale results are dropped
+	searchCancel  context.CancelFunc // cancels the in-flight remote search
+	spinner       spinner.Model
 	installing    bool
-	manualSet     map[string]bool
+	manualSet     map[pkgKey]bool
 	viewFilter    viewFilter
+	tagFilter     string // only show bookmarks with this tag
+	tagInput      textinput.Model
+	tagsPkg       pkgKey // package whose tags are being edited
+	noteInput     textarea.Model
+	notePkg       pkgKey // package whose note is being edited
+	snapshots     []*snapshot.Snapshot
+	snapshotIdx   int
+	snapshotPlan  *snapshot.Plan // restoring the selected snapshot, once compared
+	snapshotErr   error
+	columns       []string
+	sortOrder     sortOrder
+	index         *manager.Index // local catalog, once built; fills in candidate versions
+	detailPkg     string         // package shown in the split-pane detail panel
+	detailErr     error
+	detailGen     int // bumped per cursor move; stale debounce ticks are dropped
+	details       map[string]manager.PackageInfo
+	lastClickRow  int // for detecting double clicks
+	lastClickAt   time.Time
+	log           []string // output of the commands run this session
+	paletteInput  textinput.Model
+	palette       []paletteEntry
+	matches       []paletteMatch
+	paletteCursor int
+	visible       *visibleCache // shared by the Model's copies
 }
 
-func NewModel(mgr manager.PackageManager, cfg *config.Config) Model {
+// NewModel builds the UI for the given managers, starting with the first
+// system one, with the user-level ones listed alongside it. It fails if
+// the key bindings or theme in cfg are invalid.
+func NewModel(managers []manager.PackageManager, cfg *config.Config) (Model, error) {
+	keys, err := loadKeyMap(cfg.Keys)
+	if err != nil {
+		return Model{}, fmt.Errorf("keys: %w", err)
+	}
+	if err := loadTheme(cfg.Theme, cfg.Themes); err != nil {
+		return Model{}, fmt.Errorf("theme: %w", err)
+	}
+
 	ti := textinput.New()
 	ti.Placeholder = "Search packages..."
 	ti.CharLimit = 100
@@ -84,49 +198,129 @@ func NewModel(mgr manager.PackageManager, cfg *config.Config) Model {
 	pi.CharLimit = 200
 	pi.Width = 40
 
-	return Model{
+	pal := textinput.New()
+	pal.Placeholder = "Command or package name"
+	pal.CharLimit = 100
+	pal.Width = modalContentWidth - 4
+
+	tg := textinput.New()
+	tg.Placeholder = "dev, work"
+	tg.CharLimit = 200
+	tg.Width = modalContentWidth - 4
+
+	sp := spinner.New()
+	sp.Spinner = spinner.Dot
+	sp.Style = searchStyle
+
+	mgr := preferredManager(managers)
+	m := Model{
 		mgr:           mgr,
+		managers:      managers,
+		tools:         userTools(managers, mgr),
 		cfg:           cfg,
-		keys:          defaultKeyMap(),
+		keys:          keys,
+		help:          newHelp(),
 		searchInput:   ti,
 		passwordInput: pi,
+		paletteInput:  pal,
+		tagInput:      tg,
+		noteInput:     newNoteInput(),
+		spinner:       sp,
 		loading:       true,
+		columns:       configuredColumns(cfg.Columns),
+		details:       make(map[string]manager.PackageInfo),
+		visible:       &visibleCache{},
+	}
+	if len(m.tools) > 0 && len(cfg.Columns) == 0 {
+		// Tell the tools' packages from the system's
+		m.columns = append(append([]string(nil), defaultColumns...), "manager")
+	}
+
+	m.showCachedLists()
+	return m, nil
+}
+
+// showCachedLists renders the lists from the last run right away, if the
+// manager keeps them; loadPackages then refreshes them
+func (m *Model) showCachedLists() {
+	cached, ok := m.mgr.(*manager.CachedManager)
+	if !ok {
+		return
+	}
+	if installed, manual, ok := cached.Cached(); ok {
+		installed, manual = withTools(installed, manual, m.tools, cachedTools)
+		m.setPackages(bookmarkedInfo(m.cfg.Packages, installed, m.mgr, m.tools), installed, manual)
+		m.loading = false
+		m.refreshing = true
 	}
 }
 
 func (m Model) Init() tea.Cmd {
-	return m.loadPackages()
+	return tea.Batch(m.loadPackages(), m.warmIndex())
+}
+
+// warmIndex builds the local search index in the background so the first
+// search doesn't pay for parsing the package catalog
+func (m Model) warmIndex() tea.Cmd {
+	mgr := m.mgr
+	return func() tea.Msg {
+		return indexReadyMsg{mgr: mgr.Name(), index: manager.WarmIndex(mgr)}
+	}
 }
 
 func (m Model) loadPackages() tea.Cmd {
+	mgr := m.mgr
+	tools := m.tools
 	return func() tea.Msg {
 		ctx := context.Background()
 
 		// First, get all installed packages (single brew call)
-		installed, err := m.mgr.ListInstalled(ctx)
+		installed, err := mgr.ListInstalled(ctx)
 		if err != nil {
-			return packagesLoadedMsg{err: err}
+			return packagesLoadedMsg{mgr: mgr.Name(), err: err}
 		}
+		manual, _ := mgr.ListManuallyInstalled(ctx)
+		installed, manual = withTools(installed, manual, tools, listTools(ctx))
 
-		// Build a set of installed package names for quick lookup
-		installedSet := make(map[string]bool)
-		for _, pkg := range installed {
-			installedSet[pkg.Name] = true
-		}
+		bookmarked := bookmarkedInfo(m.cfg.Packages, installed, mgr, tools)
 
-		// For bookmarked packages, just create basic info and check against installed set
-		var bookmarked []manager.PackageInfo
-		for _, pkg := range m.cfg.Packages {
-			bookmarked = append(bookmarked, manager.PackageInfo{
-				Name:      pkg,
-				Installed: installedSet[pkg],
-			})
-		}
+		return packagesLoadedMsg{mgr: mgr.Name(), bookmarked: bookmarked, installed: installed, manual: manual}
+	}
+}
 
-		manual, _ := m.mgr.ListManuallyInstalled(ctx)
+// bookmarkedInfo builds info for each bookmark of mgr's packages or the
+// tools'. Installed bookmarks reuse the listed info; the rest only get a
+// name and the manager that would install them. Bookmarks of tools that
+// aren't available are left out.
+func bookmarkedInfo(bookmarks []config.Package, installed []manager.PackageInfo, mgr manager.PackageManager, tools []manager.PackageManager) []manager.PackageInfo {
+	available := map[string]bool{mgr.Name(): true}
+	for _, tool := range tools {
+		available[tool.Name()] = true
+	}
+	installedByKey := make(map[pkgKey]manager.PackageInfo)
+	for _, pkg := range installed {
+		if pkg.Manager == "" {
+			pkg.Manager = mgr.Name()
+		}
+		installedByKey[keyOf(pkg)] = pkg
+	}
 
-		return packagesLoadedMsg{bookmarked: bookmarked, installed: installed, manual: manual}
+	var bookmarked []manager.PackageInfo
+	for _, p := range bookmarks {
+		key := pkgKey{mgr: p.Manager, name: p.Name}
+		if key.mgr == "" {
+			key.mgr = mgr.Name()
+		}
+		if !available[key.mgr] {
+			continue
+		}
+		info, ok := installedByKey[key]
+		if !ok {
+			info = manager.PackageInfo{Name: p.Name, Manager: key.mgr}
+		}
+		bookmarked = append(bookmarked, info)
 	}
+	return bookmarked
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -135,23 +329,58 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		m.height = msg.Height
 		m.ensureCursorVisible()
-		return m, nil
+		return m, m.followCursor()
 
 	case packagesLoadedMsg:
+		if msg.mgr != m.mgr.Name() {
+			// Loaded for a manager we've since switched away from
+			return m, nil
+		}
 		m.loading = false
 		if msg.err != nil {
 			m.statusMsg = fmt.Sprintf("Error: %v", msg.err)
 			m.statusErr = true
+			if m.refreshing {
+				// Keep showing the cached lists rather than an empty screen
+				m.refreshing = false
+				return m, nil
+			}
 		}
-		m.manualSet = make(map[string]bool)
-		for _, pkg := range msg.manual {
-			m.manualSet[pkg.Name] = true
+		m.refreshing = false
+		m.setPackages(msg.bookmarked, msg.installed, msg.manual)
+		m.applyIndex()
+		return m, m.followCursor()
+
+	case indexReadyMsg:
+		if msg.mgr != m.mgr.Name() {
+			return m, nil
 		}
-		m.buildItemList(msg.bookmarked, msg.installed)
+		m.index = msg.index
+		m.applyIndex()
 		return m, nil
 
+	case searchDebounceMsg:
+		if msg.gen != m.searchGen {
+			// More typing happened since this was scheduled
+			return m, nil
+		}
+		return m, m.startSearch()
+
+	case spinner.TickMsg:
+		if !m.searching {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
 	case searchResultsMsg:
+		if msg.gen != m.searchGen {
+			// Results for an older query arrived late
+			return m, nil
+		}
 		m.searching = false
+		m.searchCancel = nil
 		if msg.err != nil {
 			m.statusMsg = fmt.Sprintf("Search error: %v", msg.err)
 			m.statusErr = true
@@ -159,20 +388,49 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		m.filtered = make([]packageItem, 0, len(msg.results))
 		for _, info := range msg.results {
+			key := keyOf(info)
 			m.filtered = append(m.filtered, packageItem{
 				info:       info,
-				bookmarked: m.cfg.IsBookmarked(info.Name),
+				bookmarked: m.cfg.IsBookmarked(m.bookmarkKey(key)),
+				tags:       m.cfg.Tags(m.bookmarkKey(key)),
+				note:       m.cfg.Note(m.bookmarkKey(key)),
 			})
 		}
+		m.listChanged()
 		m.cursor = 0
 		m.scroll = 0
+		return m, m.followCursor()
+
+	case detailDebounceMsg:
+		if msg.gen != m.detailGen {
+			// The cursor moved on before the debounce ran out
+			return m, nil
+		}
+		return m, m.fetchDetail(m.detailPkg)
+
+	case packageDetailMsg:
+		if msg.err == nil {
+			m.details[msg.pkg] = msg.info
+		}
+		if msg.pkg == m.detailPkg {
+			m.detailErr = msg.err
+		}
+		return m, nil
+
+	case packageFilesMsg:
+		if msg.pkg == m.infoPkg {
+			m.infoFiles = msg.files
+			m.filesLoaded = true
+			m.filesErr = msg.err
+		}
 		return m, nil
 
 	case packageInfoMsg:
 		if msg.err != nil {
 			m.infoText = fmt.Sprintf("Error loading info: %v", msg.err)
 		} else {
-			m.infoText = formatInfo(msg.info)
+			m.infoText = formatInfo(msg.info, m.cfg.Note(m.bookmarkManager(m.managerOf(msg.info.Name).Name()), msg.info.Name))
+			m.details[msg.info.Name] = msg.info
 		}
 		m.viewMode = viewInfo
 		return m, nil
@@ -185,11 +443,128 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		} else {
 			m.statusMsg = fmt.Sprintf("Installed %s", msg.pkg)
 			m.statusErr = false
-			m.updateInstallStatus(msg.pkg, true)
+			m.updateInstallStatus(pkgKey{mgr: msg.mgr, name: msg.pkg}, true)
+			if msg.caveats != "" {
+				m.openInfo(msg.pkg, fmt.Sprintf("Caveats for %s:\n\n%s", msg.pkg, msg.caveats))
+				return m, m.forgetDetail(msg.pkg)
+			}
+		}
+		m.viewMode = viewNormal
+		return m, m.forgetDetail(msg.pkg)
+
+	case cleanupResultMsg:
+		m.installing = false
+		m.viewMode = viewNormal
+		if msg.err != nil {
+			m.statusMsg = fmt.Sprintf("Clean up failed: %v", msg.err)
+			m.statusErr = true
+			return m, nil
+		}
+		m.statusMsg = "Cleaned up"
+		m.statusErr = false
+		return m, m.refresh()
+
+	case tagInstallResultMsg:
+		m.installing = false
+		m.viewMode = viewNormal
+		if msg.err != nil {
+			// Some of the packages may have gone in before the failure
+			m.statusMsg = fmt.Sprintf("Install failed: %v", msg.err)
+			m.statusErr = true
+			return m, m.refresh()
 		}
+		m.statusMsg = fmt.Sprintf("Installed %d packages tagged %s", len(msg.pkgs), msg.tag)
+		m.statusErr = false
+		cmds := make([]tea.Cmd, len(msg.pkgs))
+		for i, key := range msg.pkgs {
+			m.updateInstallStatus(key, true)
+			cmds[i] = m.forgetDetail(key.name)
+		}
+		return m, tea.Batch(cmds...)
+
+	case undoResultMsg:
+		m.installing = false
 		m.viewMode = viewNormal
+		if msg.err != nil {
+			m.statusMsg = fmt.Sprintf("Undo failed: %v", msg.err)
+			m.statusErr = true
+		} else {
+			m.statusMsg = fmt.Sprintf("Undid %s", msg.label)
+			m.statusErr = false
+		}
+		return m, m.refresh()
+
+	case snapshotComparedMsg:
+		if s, ok := m.selectedSnapshot(); ok && m.viewMode == viewSnapshots && s.Name == msg.name {
+			m.snapshotPlan = &msg.plan
+			m.snapshotErr = msg.err
+		}
 		return m, nil
 
+	case snapshotSavedMsg:
+		if msg.err != nil {
+			m.statusMsg = fmt.Sprintf("Saving snapshot failed: %v", msg.err)
+			m.statusErr = true
+			return m, nil
+		}
+		m.statusMsg = fmt.Sprintf("Saved snapshot %s", msg.name)
+		m.statusErr = false
+		if m.viewMode == viewSnapshots {
+			return m, m.openSnapshots()
+		}
+		return m, nil
+
+	case snapshotRestoredMsg:
+		m.installing = false
+		m.viewMode = viewNormal
+		m.statusMsg = fmt.Sprintf("Restored snapshot %s", msg.name)
+		m.statusErr = false
+		// Bookmarks come back even if some versions couldn't
+		if msg.bookmarks != nil {
+			m.cfg.Packages = msg.bookmarks
+			if err := m.cfg.Save(); err != nil {
+				m.statusMsg = fmt.Sprintf("Restored snapshot %s, but saving bookmarks failed: %v", msg.name, err)
+				m.statusErr = true
+			}
+			if m.tagFilter != "" && len(m.cfg.Tagged(m.tagFilter)) == 0 {
+				m.setTagFilter("")
+			}
+		}
+		if msg.err != nil {
+			m.statusMsg = fmt.Sprintf("Restore failed: %v", msg.err)
+			m.statusErr = true
+		}
+		return m, m.refresh()
+
+	case commandOutputMsg:
+		m.appendLog(msg)
+		return m.Update(msg.result)
+
+	case hookFailedMsg:
+		next, cmd := m.Update(msg.result)
+		if updated, ok := next.(Model); ok {
+			m = updated
+		}
+		m.statusMsg = fmt.Sprintf("%s, but %v", m.statusMsg, msg.errs[0])
+		if len(msg.errs) > 1 {
+			m.statusMsg += fmt.Sprintf(" (and %d more, see the log)", len(msg.errs)-1)
+		}
+		m.statusErr = true
+		return m, cmd
+
+	case upgradeResultMsg:
+		m.installing = false
+		m.viewMode = viewNormal
+		if msg.err != nil {
+			// The upgrade may have got partway
+			m.statusMsg = fmt.Sprintf("Upgrade failed: %v", msg.err)
+			m.statusErr = true
+			return m, m.refresh()
+		}
+		m.statusMsg = fmt.Sprintf("Upgraded %s", msg.pkg)
+		m.statusErr = false
+		return m, m.refresh()
+
 	case uninstallResultMsg:
 		m.installing = false
 		if msg.err != nil {
@@ -198,9 +573,19 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		} else {
 			m.statusMsg = fmt.Sprintf("Uninstalled %s", msg.pkg)
 			m.statusErr = false
-			m.updateInstallStatus(msg.pkg, false)
+			m.updateInstallStatus(pkgKey{mgr: msg.mgr, name: msg.pkg}, false)
 		}
 		m.viewMode = viewNormal
+		return m, m.forgetDetail(msg.pkg)
+
+	case historyLoadedMsg:
+		if m.viewMode != viewInfo || m.infoTitle != "History" {
+			return m, nil
+		}
+		m.infoText = formatHistory(msg.entries)
+		if msg.err != nil {
+			m.infoText = fmt.Sprintf("Error reading boxy's history: %v\n\n%s", msg.err, m.infoText)
+		}
 		return m, nil
 
 	case bookmarkToggledMsg:
@@ -214,177 +599,502 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case tea.KeyMsg:
 		return m.handleKey(msg)
+
+	case tea.MouseMsg:
+		return m.handleMouse(msg)
 	}
 
 	return m, nil
 }
 
 func (m *Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
 	switch m.viewMode {
 	case viewSearch:
-		return m.handleSearchKey(msg)
+		_, cmd = m.handleSearchKey(msg)
 	case viewInfo:
-		return m.handleInfoKey(msg)
+		_, cmd = m.handleInfoKey(msg)
 	case viewConfirm:
-		return m.handleConfirmKey(msg)
+		_, cmd = m.handleConfirmKey(msg)
 	case viewSudoPassword:
-		return m.handleSudoPasswordKey(msg)
+		_, cmd = m.handleSudoPasswordKey(msg)
+	case viewHelp:
+		_, cmd = m.handleHelpKey(msg)
+	case viewPalette:
+		_, cmd = m.handlePaletteKey(msg)
+	case viewTags:
+		_, cmd = m.handleTagsKey(msg)
+	case viewNote:
+		_, cmd = m.handleNoteKey(msg)
+	case viewSnapshots:
+		_, cmd = m.handleSnapshotsKey(msg)
 	default:
-		return m.handleNormalKey(msg)
+		_, cmd = m.handleNormalKey(msg)
 	}
+	// Keys that move the cursor or change the list move the detail panel
+	return m, tea.Batch(cmd, m.followCursor())
 }
 
 func (m *Model) handleNormalKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch {
-	case msg.String() == "q":
+	case key.Matches(msg, m.keys.Quit):
 		return m, tea.Quit
 
-	case msg.String() == "up" || msg.String() == "k":
-		if m.cursor > 0 {
-			m.cursor--
-			m.ensureCursorVisible()
-		}
+	case key.Matches(msg, m.keys.Help):
+		m.viewMode = viewHelp
 
-	case msg.String() == "down" || msg.String() == "j":
-		items := m.visibleItems()
-		if m.cursor < len(items)-1 {
-			m.cursor++
-			m.ensureCursorVisible()
-		}
+	case key.Matches(msg, m.keys.Up):
+		m.moveCursor(-1)
+
+	case key.Matches(msg, m.keys.Down):
+		m.moveCursor(1)
+
+	case key.Matches(msg, m.keys.PageUp):
+		m.moveCursor(-m.maxVisibleItems())
+
+	case key.Matches(msg, m.keys.PageDown):
+		m.moveCursor(m.maxVisibleItems())
 
-	case msg.String() == "/":
+	case key.Matches(msg, m.keys.Top):
+		m.moveCursor(-len(m.visibleItems()))
+
+	case key.Matches(msg, m.keys.Bottom):
+		m.moveCursor(len(m.visibleItems()))
+
+	case key.Matches(msg, m.keys.Search):
+		// Edit the remote query if results are showing, otherwise filter locally
+		m.setSearchRemote(m.filtered != nil)
 		m.viewMode = viewSearch
 		m.searchInput.Focus()
 		return m, textinput.Blink
 
-	case msg.String() == "esc":
-		if m.filtered != nil {
-			// Add any newly bookmarked packages to the main items list
-			m.mergeBookmarkedItems()
-			m.filtered = nil
-			m.searchInput.SetValue("")
-			m.cursor = 0
-			m.scroll = 0
-		}
-
-	case msg.String() == "enter":
-		items := m.visibleItems()
-		if len(items) > 0 && m.cursor < len(items) {
-			pkg := items[m.cursor].info.Name
-			m.viewMode = viewInfo
-			m.infoText = "Loading..."
-			return m, m.fetchInfo(pkg)
-		}
-
-	case msg.String() == "i":
-		items := m.visibleItems()
-		if len(items) > 0 && m.cursor < len(items) {
-			item := items[m.cursor]
-			if !item.info.Installed {
-				m.confirmPkg = item.info.Name
-				m.confirmAct = confirmInstall
-				m.viewMode = viewConfirm
-			}
+	case key.Matches(msg, m.keys.Escape):
+		if m.filtered != nil || m.filterQuery != "" {
+			m.clearSearch()
 		}
 
-	case msg.String() == "u":
-		items := m.visibleItems()
-		if len(items) > 0 && m.cursor < len(items) {
-			item := items[m.cursor]
-			if item.info.Installed {
-				m.confirmPkg = item.info.Name
-				m.confirmAct = confirmUninstall
-				m.viewMode = viewConfirm
-			}
-		}
+	case key.Matches(msg, m.keys.Info):
+		return m, m.showInfo()
+
+	case key.Matches(msg, m.keys.Install):
+		m.requestInstall()
+
+	case key.Matches(msg, m.keys.Uninstall):
+		m.requestUninstall()
 
-	case msg.String() == "v":
-		m.viewFilter = (m.viewFilter + 1) % 3
+	case key.Matches(msg, m.keys.Upgrade):
+		m.requestUpgrade()
+
+	case key.Matches(msg, m.keys.View):
+		m.cycleView()
+
+	case key.Matches(msg, m.keys.FilterInstalled) && m.filtered != nil:
+		m.searchFilters.installed = (m.searchFilters.installed + 1) % 3
 		m.cursor = 0
 		m.scroll = 0
 
-	case msg.String() == "b":
-		items := m.visibleItems()
-		if len(items) > 0 && m.cursor < len(items) {
-			pkg := items[m.cursor].info.Name
-			bookmarked := m.cfg.ToggleBookmark(pkg)
-			m.cfg.Save()
-			m.updateBookmarkStatus(pkg, bookmarked)
-			return m, func() tea.Msg {
-				return bookmarkToggledMsg{pkg: pkg, bookmarked: bookmarked}
-			}
-		}
+	case key.Matches(msg, m.keys.FilterNoise) && m.filtered != nil:
+		m.searchFilters.hideNoise = !m.searchFilters.hideNoise
+		m.cursor = 0
+		m.scroll = 0
+
+	case key.Matches(msg, m.keys.FilterManager) && m.filtered != nil:
+		m.searchFilters.source = nextSource(m.searchFilters.source, m.filtered)
+		m.cursor = 0
+		m.scroll = 0
+
+	case key.Matches(msg, m.keys.Sort):
+		m.cycleSort()
+
+	case key.Matches(msg, m.keys.Bookmark):
+		return m, m.toggleBookmark()
+
+	case key.Matches(msg, m.keys.Tags):
+		return m, m.editTags()
+
+	case key.Matches(msg, m.keys.TagFilter):
+		m.cycleTagFilter()
+
+	case key.Matches(msg, m.keys.Note):
+		return m, m.editNote()
+
+	case key.Matches(msg, m.keys.Palette):
+		return m, m.openPalette()
+
+	case key.Matches(msg, m.keys.History):
+		return m, m.openHistory()
+
+	case key.Matches(msg, m.keys.Undo):
+		m.requestUndo()
+
+	case key.Matches(msg, m.keys.Snapshots):
+		return m, m.openSnapshots()
 	}
 
 	return m, nil
 }
 
 func (m *Model) handleSearchKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "esc":
+	switch {
+	case matchesNav(msg, m.keys.Escape):
 		m.viewMode = viewNormal
 		m.searchInput.Blur()
-		m.searchInput.SetValue("")
-		m.filtered = nil
+		m.clearSearch()
+		return m, nil
+
+	case key.Matches(msg, m.keys.Search):
+		// Toggle between filtering local packages and searching remotely
+		var cmd tea.Cmd
+		if !m.searchRemote {
+			m.setSearchRemote(true)
+			m.filterQuery = ""
+			cmd = m.scheduleSearch()
+		} else {
+			m.cancelSearch()
+			m.setSearchRemote(false)
+			if m.filtered != nil {
+				m.mergeBookmarkedItems()
+				m.filtered = nil
+				m.listChanged()
+			}
+			m.filterQuery = m.searchInput.Value()
+		}
 		m.cursor = 0
 		m.scroll = 0
+		return m, cmd
+
+	case matchesNav(msg, m.keys.Up):
+		m.moveCursor(-1)
+		return m, nil
+
+	case matchesNav(msg, m.keys.Down):
+		m.moveCursor(1)
+		return m, nil
+
+	case matchesNav(msg, m.keys.PageUp):
+		m.moveCursor(-m.maxVisibleItems())
 		return m, nil
 
-	case "enter":
-		query := m.searchInput.Value()
-		if query != "" {
-			m.searching = true
+	case matchesNav(msg, m.keys.PageDown):
+		m.moveCursor(m.maxVisibleItems())
+		return m, nil
+
+	case matchesNav(msg, m.keys.Submit):
+		if !m.searchRemote {
+			// Keep the filter and hand the keys back to the list
 			m.viewMode = viewNormal
 			m.searchInput.Blur()
-			return m, m.searchPackages(query)
+			return m, nil
 		}
-		return m, nil
+		m.viewMode = viewNormal
+		m.searchInput.Blur()
+		if m.searchInput.Value() == "" {
+			return m, nil
+		}
+		// Search now rather than waiting out the debounce, unless the
+		// debounced search for this exact query is already running
+		if m.searching && m.searchCancel != nil {
+			return m, nil
+		}
+		m.searchGen++
+		return m, m.startSearch()
 	}
 
+	prev := m.searchInput.Value()
 	var cmd tea.Cmd
 	m.searchInput, cmd = m.searchInput.Update(msg)
+	if m.searchInput.Value() == prev {
+		return m, cmd
+	}
+	if m.searchRemote {
+		return m, tea.Batch(cmd, m.scheduleSearch())
+	}
+	m.filterQuery = m.searchInput.Value()
+	m.cursor = 0
+	m.scroll = 0
 	return m, cmd
 }
 
+// scheduleSearch arranges for a remote search of the current query once
+// typing pauses. Every call bumps the generation, so only the last
+// scheduled search runs and results from earlier queries are ignored.
+func (m *Model) scheduleSearch() tea.Cmd {
+	m.cancelSearch()
+	if m.searchInput.Value() == "" {
+		return nil
+	}
+	gen := m.searchGen
+	return tea.Tick(searchDebounce, func(time.Time) tea.Msg {
+		return searchDebounceMsg{gen: gen}
+	})
+}
+
+// startSearch runs the remote search for the current query
+func (m *Model) startSearch() tea.Cmd {
+	query := m.searchInput.Value()
+	if query == "" {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.searchCancel = cancel
+	m.searching = true
+	return tea.Batch(m.searchPackages(ctx, query, m.searchGen), m.spinner.Tick)
+}
+
+// cancelSearch stops any in-flight search and invalidates its results
+func (m *Model) cancelSearch() {
+	m.searchGen++
+	if m.searchCancel != nil {
+		m.searchCancel()
+		m.searchCancel = nil
+	}
+	m.searching = false
+}
+
+// setSearchRemote switches the search bar between local filtering and
+// remote search
+func (m *Model) setSearchRemote(remote bool) {
+	m.searchRemote = remote
+	if remote {
+		m.searchInput.Placeholder = "Search packages..."
+	} else {
+		m.searchInput.Placeholder = "Filter installed and bookmarked..."
+	}
+}
+
+// clearSearch drops the local filter and any remote results
+func (m *Model) clearSearch() {
+	m.cancelSearch()
+	if m.filtered != nil {
+		// Add any newly bookmarked packages to the main items list
+		m.mergeBookmarkedItems()
+		m.filtered = nil
+		m.listChanged()
+	}
+	m.filterQuery = ""
+	m.setSearchRemote(false)
+	m.searchInput.SetValue("")
+	m.cursor = 0
+	m.scroll = 0
+}
+
 func (m *Model) handleInfoKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	if msg.String() == "esc" || msg.String() == "enter" || msg.String() == "q" {
+	switch {
+	case key.Matches(msg, m.keys.Escape, m.keys.Info, m.keys.Quit):
 		m.viewMode = viewNormal
 		m.infoText = ""
+		m.infoScroll = 0
+		m.infoPkg = ""
+		m.infoFiles = nil
+		m.filesLoaded = false
+		m.filesErr = nil
+		m.showFiles = false
+
+	case key.Matches(msg, m.keys.Files):
+		if m.infoPkg == "" {
+			break
+		}
+		m.showFiles = !m.showFiles
+		m.infoScroll = 0
+		if m.showFiles && !m.filesLoaded {
+			return m, m.fetchFiles(m.infoPkg)
+		}
+
+	case key.Matches(msg, m.keys.Up):
+		m.scrollInfo(-1)
+
+	case key.Matches(msg, m.keys.Down):
+		m.scrollInfo(1)
+
+	case key.Matches(msg, m.keys.PageUp):
+		m.scrollInfo(-m.infoPageSize())
+
+	case key.Matches(msg, m.keys.PageDown):
+		m.scrollInfo(m.infoPageSize())
+
+	case key.Matches(msg, m.keys.Top):
+		m.scrollInfo(-len(m.infoLines()))
+
+	case key.Matches(msg, m.keys.Bottom):
+		m.scrollInfo(len(m.infoLines()))
 	}
 	return m, nil
 }
 
+// openInfo shows the info modal for pkg with the given text, resetting
+// scroll and file listing state from any previous package
+func (m *Model) openInfo(pkg, text string) {
+	m.viewMode = viewInfo
+	m.infoTitle = ""
+	m.infoText = text
+	m.infoScroll = 0
+	m.infoPkg = pkg
+	m.infoFiles = nil
+	m.filesLoaded = false
+	m.filesErr = nil
+	m.showFiles = false
+}
+
+// scrollInfo moves the info modal viewport by delta lines, clamped to the content
+func (m *Model) scrollInfo(delta int) {
+	maxScroll := len(m.infoLines()) - m.infoPageSize()
+	m.infoScroll += delta
+	if m.infoScroll > maxScroll {
+		m.infoScroll = maxScroll
+	}
+	if m.infoScroll < 0 {
+		m.infoScroll = 0
+	}
+}
+
 func (m *Model) handleConfirmKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "y":
-		if m.mgr.NeedsSudo() && !manager.SudoCached() {
-			m.viewMode = viewSudoPassword
-			m.passwordInput.SetValue("")
-			m.passwordInput.Focus()
-			return m, textinput.Blink
+	switch {
+	case key.Matches(msg, m.keys.Confirm):
+		return m, m.confirm()
+
+	case key.Matches(msg, m.keys.Cancel, m.keys.Escape):
+		m.cancelConfirm()
+	}
+	return m, nil
+}
+
+// selected returns the package under the cursor
+func (m Model) selected() (packageItem, bool) {
+	items := m.visibleItems()
+	if m.cursor >= len(items) {
+		return packageItem{}, false
+	}
+	return items[m.cursor], true
+}
+
+func (m *Model) requestInstall() {
+	if item, ok := m.selected(); ok && !item.info.Installed {
+		m.requestPackage(item, confirmInstall)
+	}
+}
+
+func (m *Model) requestUninstall() {
+	if item, ok := m.selected(); ok && item.info.Installed {
+		m.requestPackage(item, confirmUninstall)
+	}
+}
+
+func (m *Model) requestUpgrade() {
+	if item, ok := m.selected(); ok && item.info.Installed {
+		m.requestPackage(item, confirmUpgrade)
+	}
+}
+
+// requestPackage asks to act on one package with the manager it's listed
+// under
+func (m *Model) requestPackage(item packageItem, act confirmAction) {
+	m.confirmPkg = item.info.Name
+	m.confirmMgr = m.managerNamed(item.info.Manager)
+	m.confirmAct = act
+	m.viewMode = viewConfirm
+}
+
+func (m *Model) toggleBookmark() tea.Cmd {
+	item, ok := m.selected()
+	if !ok {
+		return nil
+	}
+	pkg := item.info.Name
+	bookmarked := m.cfg.ToggleBookmark(m.bookmarkKey(keyOf(item.info)))
+	m.cfg.Save()
+	m.updateBookmarkStatus(keyOf(item.info), bookmarked)
+	return func() tea.Msg {
+		return bookmarkToggledMsg{pkg: pkg, bookmarked: bookmarked}
+	}
+}
+
+func (m *Model) cycleView() {
+	m.viewFilter = (m.viewFilter + 1) % 3
+	m.cursor = 0
+	m.scroll = 0
+}
+
+func (m *Model) cycleSort() {
+	m.sortOrder = (m.sortOrder + 1) % numSortOrders
+	m.cursor = 0
+	m.scroll = 0
+}
+
+// confirm answers yes to the confirm modal, asking for the sudo password
+// first if it isn't cached
+func (m *Model) confirm() tea.Cmd {
+	if m.confirmNeedsSudo() && !manager.SudoCached() {
+		m.viewMode = viewSudoPassword
+		m.passwordInput.SetValue("")
+		m.passwordInput.Focus()
+		return textinput.Blink
+	}
+	return m.startAction()
+}
+
+// confirmNeedsSudo reports whether the pending action runs commands under
+// sudo. A restore or tag install can span managers; everything else uses
+// one.
+func (m Model) confirmNeedsSudo() bool {
+	switch m.confirmAct {
+	case confirmRestore:
+		return m.restorePlan.NeedsSudo()
+	case confirmInstallTag:
+		for _, op := range m.installOps(m.confirmPkgs) {
+			if op.mgr.NeedsSudo() {
+				return true
+			}
 		}
-		return m, m.startAction()
+		return false
+	}
+	return m.confirmManager().NeedsSudo()
+}
+
+// confirmManager returns the manager that runs the pending action
+func (m Model) confirmManager() manager.PackageManager {
+	if m.confirmMgr != nil {
+		return m.confirmMgr
+	}
+	return m.mgr
+}
+
+func (m *Model) cancelConfirm() {
+	m.viewMode = viewNormal
+	m.confirmPkg = ""
+	m.confirmPkgs = nil
+	m.confirmMgr = nil
+}
+
+// showInfo opens the info modal for the package under the cursor
+func (m *Model) showInfo() tea.Cmd {
+	item, ok := m.selected()
+	if !ok {
+		return nil
+	}
+	pkg := item.info.Name
+	m.openInfo(pkg, "Loading...")
+	return m.fetchInfo(pkg)
+}
 
-	case "n", "esc":
+func (m *Model) handleHelpKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if key.Matches(msg, m.keys.Help, m.keys.Escape, m.keys.Quit) {
 		m.viewMode = viewNormal
-		m.confirmPkg = ""
 	}
 	return m, nil
 }
 
 func (m *Model) handleSudoPasswordKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "enter":
+	switch {
+	case matchesNav(msg, m.keys.Submit):
 		m.sudoPassword = m.passwordInput.Value()
 		m.passwordInput.SetValue("")
 		m.passwordInput.Blur()
 		return m, m.startAction()
 
-	case "esc":
+	case matchesNav(msg, m.keys.Escape):
 		m.passwordInput.SetValue("")
 		m.passwordInput.Blur()
-		m.viewMode = viewNormal
-		m.confirmPkg = ""
+		m.cancelConfirm()
 		return m, nil
 	}
 
@@ -395,88 +1105,216 @@ func (m *Model) handleSudoPasswordKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 func (m *Model) startAction() tea.Cmd {
 	pkg := m.confirmPkg
+	mgr := m.confirmManager()
 	password := m.sudoPassword
 	m.sudoPassword = ""
+	m.confirmMgr = nil
 	m.installing = true
 	m.viewMode = viewNormal
 
-	if m.confirmAct == confirmInstall {
-		return m.installPackage(pkg, password)
+	switch m.confirmAct {
+	case confirmInstall:
+		return m.installPackage(mgr, pkg, password)
+	case confirmUpgrade:
+		return m.upgradePackage(mgr, pkg, password)
+	case confirmCleanup:
+		return m.cleanup(password)
+	case confirmInstallTag:
+		return m.installTag(pkg, m.confirmPkgs, password)
+	case confirmUndo:
+		return m.undo(m.undoPlan, password)
+	case confirmRestore:
+		return m.restoreSnapshot(m.restorePlan, password)
 	}
-	return m.uninstallPackage(pkg, password)
+	return m.uninstallPackage(mgr, pkg, password)
 }
 
-func (m Model) searchPackages(query string) tea.Cmd {
+// searchPackages searches the current manager and, alongside it, the
+// tools listed with it
+func (m Model) searchPackages(ctx context.Context, query string, gen int) tea.Cmd {
+	mgr := m.mgr
+	tools := m.tools
 	return func() tea.Msg {
-		ctx := context.Background()
-		results, err := m.mgr.Search(ctx, query)
+		toolResults := searchTools(ctx, tools, query)
+		results, err := searchManager(ctx, mgr, query)
 		if err != nil {
-			return searchResultsMsg{err: err}
+			return searchResultsMsg{gen: gen, err: err}
 		}
+		results = append(results, <-toolResults...)
 
-		// Get installed list once instead of checking each result individually
-		installedList, _ := m.mgr.ListInstalled(ctx)
-		installedSet := make(map[string]bool)
-		for _, pkg := range installedList {
-			installedSet[pkg.Name] = true
-		}
+		// Shell searches print results in catalog order; put the best matches first
+		manager.Rank(results, query)
 
-		for i := range results {
-			results[i].Installed = installedSet[results[i].Name]
-		}
+		return searchResultsMsg{gen: gen, results: results}
+	}
+}
 
-		return searchResultsMsg{results: results}
+// searchManager searches one manager, marking the results it has installed
+func searchManager(ctx context.Context, mgr manager.PackageManager, query string) ([]manager.PackageInfo, error) {
+	results, err := mgr.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get installed list once instead of checking each result individually
+	installedList, _ := mgr.ListInstalled(ctx)
+	installedByName := make(map[string]manager.PackageInfo)
+	for _, pkg := range installedList {
+		installedByName[pkg.Name] = pkg
+	}
+
+	for i := range results {
+		installed, ok := installedByName[results[i].Name]
+		results[i].Installed = ok
+		if ok && installed.Version != "" {
+			// Results carry the version that would be installed, as the
+			// index does; an installed package shows its own
+			if results[i].CandidateVersion == "" {
+				results[i].CandidateVersion = results[i].Version
+			}
+			results[i].Version = installed.Version
+		}
+		if results[i].Manager == "" {
+			results[i].Manager = mgr.Name()
+		}
 	}
+	return results, nil
 }
 
 func (m Model) fetchInfo(pkg string) tea.Cmd {
+	mgr := m.managerOf(pkg)
 	return func() tea.Msg {
 		ctx := context.Background()
-		info, err := m.mgr.GetInfo(ctx, pkg)
+		info, err := mgr.GetInfo(ctx, pkg)
 		return packageInfoMsg{info: info, err: err}
 	}
 }
 
-func (m Model) installPackage(pkg string, password string) tea.Cmd {
-	return m.runCommand("install", pkg, password, func(err error) tea.Msg {
-		return installResultMsg{pkg: pkg, err: err}
+func (m Model) fetchFiles(pkg string) tea.Cmd {
+	mgr := m.managerOf(pkg)
+	return func() tea.Msg {
+		files, err := mgr.Files(context.Background(), pkg)
+		return packageFilesMsg{pkg: pkg, files: files, err: err}
+	}
+}
+
+func (m Model) installPackage(mgr manager.PackageManager, pkg string, password string) tea.Cmd {
+	return m.runCommand(mgr, "install", []string{pkg}, password, func(err error) tea.Msg {
+		msg := installResultMsg{mgr: mgr.Name(), pkg: pkg, err: err}
+		if err == nil {
+			// Caveats are printed with the install output, which we don't show,
+			// so fetch them from the package info instead
+			if info, infoErr := mgr.GetInfo(context.Background(), pkg); infoErr == nil {
+				msg.caveats = info.Caveats
+			}
+		}
+		return msg
+	})
+}
+
+func (m Model) uninstallPackage(mgr manager.PackageManager, pkg string, password string) tea.Cmd {
+	return m.runCommand(mgr, "uninstall", []string{pkg}, password, func(err error) tea.Msg {
+		return uninstallResultMsg{mgr: mgr.Name(), pkg: pkg, err: err}
 	})
 }
 
-func (m Model) uninstallPackage(pkg string, password string) tea.Cmd {
-	return m.runCommand("uninstall", pkg, password, func(err error) tea.Msg {
-		return uninstallResultMsg{pkg: pkg, err: err}
+func (m Model) upgradePackage(mgr manager.PackageManager, pkg string, password string) tea.Cmd {
+	return m.runCommand(mgr, "upgrade", []string{pkg}, password, func(err error) tea.Msg {
+		return upgradeResultMsg{pkg: pkg, err: err}
 	})
 }
 
-func (m Model) runCommand(action, pkg, password string, done func(error) tea.Msg) tea.Cmd {
+func (m Model) cleanup(password string) tea.Cmd {
+	return m.runCommand(m.mgr, "cleanup", nil, password, func(err error) tea.Msg {
+		return cleanupResultMsg{err: err}
+	})
+}
+
+// runCommand runs a manager command, recording it in the history and its
+// output for the log before handing the result to done. The config's hooks
+// for the action run around it: a failing pre hook stops the operation, a
+// failing post hook is reported once it's done.
+func (m Model) runCommand(mgr manager.PackageManager, action string, pkgs []string, password string, done func(error) tea.Msg) tea.Cmd {
+	op := m.operation(mgr, action, pkgs)
 	return func() tea.Msg {
-		cmd := m.mgr.Command(context.Background(), action, pkg)
-		if password != "" {
-			cmd = injectSudoStdin(cmd, password)
+		var steps []commandOutputMsg
+		hookErrs, err := op.run(password, nil, &steps)
+		return chainOutput(steps, withHookErrs(done(err), hookErrs))
+	}
+}
+
+// execCommand runs a manager command and records it in the history, or as
+// part of recording if it's one step of a larger operation, like an undo.
+// The caller fills in the result.
+func execCommand(mgr manager.PackageManager, action string, pkgs []string, password string, recording *manager.Recording) commandOutputMsg {
+	run := func(cmd *exec.Cmd) (string, error) {
+		if password != "" && mgr.NeedsSudo() {
+			sudoCmd, err := injectSudoStdin(cmd, password)
+			if err != nil {
+				return "", err
+			}
+			cmd = sudoCmd
 		}
-		err := cmd.Run()
-		return done(err)
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+	if recording != nil {
+		command, output, err := recording.Run(action, pkgs, run)
+		return commandOutputMsg{command: command, output: output, err: err}
+	}
+	rec := manager.RunRecorded(context.Background(), mgr, action, pkgs, run)
+	return commandOutputMsg{
+		command:    rec.Command,
+		output:     rec.Output,
+		err:        rec.Err,
+		historyErr: rec.HistoryErr,
 	}
 }
 
 // injectSudoStdin rebuilds the command with sudo -S and pipes the password via stdin.
-func injectSudoStdin(cmd *exec.Cmd, password string) *exec.Cmd {
-	// The existing command is "sudo <args...>" — replace with "sudo -S <args...>"
+// A command that couldn't be built, or that doesn't run sudo, is refused
+// rather than run as root.
+func injectSudoStdin(cmd *exec.Cmd, password string) (*exec.Cmd, error) {
+	if cmd.Err != nil {
+		return nil, cmd.Err
+	}
+	// The existing command is "sudo <args...>" — replace with "sudo -S -p '' <args...>",
+	// which reads the password from stdin without printing a prompt
 	args := cmd.Args
-	newArgs := []string{"-S"}
-	if len(args) > 1 {
-		newArgs = append(newArgs, args[1:]...)
+	if len(args) < 2 || args[0] != "sudo" {
+		return nil, fmt.Errorf("won't run %q with the sudo password, as it doesn't start with sudo", strings.Join(args, " "))
 	}
+	newArgs := append([]string{"-S", "-p", ""}, args[1:]...)
 	newCmd := exec.Command("sudo", newArgs...)
 	newCmd.Stdin = strings.NewReader(password + "\n")
-	return newCmd
+	return newCmd, nil
+}
+
+// setPackages replaces the package lists, keeping the cursor in range
+func (m *Model) setPackages(bookmarked, installed, manual []manager.PackageInfo) {
+	m.manualSet = make(map[pkgKey]bool)
+	for _, pkg := range manual {
+		if pkg.Manager == "" {
+			pkg.Manager = m.mgr.Name()
+		}
+		m.manualSet[keyOf(pkg)] = true
+	}
+	m.buildItemList(bookmarked, installed)
+
+	if items := m.visibleItems(); m.cursor >= len(items) {
+		m.cursor = max(len(items)-1, 0)
+	}
+	m.ensureCursorVisible()
 }
 
+// buildItemList lists the bookmarks, as from bookmarkedInfo, and the
+// installed packages that aren't bookmarked. Items are told apart by
+// manager and name, as a tool may have a package of the same name as the
+// current manager's.
 func (m *Model) buildItemList(bookmarked, installed []manager.PackageInfo) {
-	bookmarkedNames := make(map[string]bool)
-	for _, pkg := range m.cfg.Packages {
-		bookmarkedNames[pkg] = true
+	bookmarkedKeys := make(map[pkgKey]bool)
+	for _, info := range bookmarked {
+		bookmarkedKeys[keyOf(info)] = true
 	}
 
 	m.items = nil
@@ -489,7 +1327,10 @@ func (m *Model) buildItemList(bookmarked, installed []manager.PackageInfo) {
 	}
 
 	for _, info := range installed {
-		if !bookmarkedNames[info.Name] {
+		if info.Manager == "" {
+			info.Manager = m.mgr.Name()
+		}
+		if !bookmarkedKeys[keyOf(info)] {
 			m.items = append(m.items, packageItem{
 				info:       info,
 				bookmarked: false,
@@ -497,40 +1338,76 @@ func (m *Model) buildItemList(bookmarked, installed []manager.PackageInfo) {
 		}
 	}
 
+	for i := range m.items {
+		key := keyOf(m.items[i].info)
+		m.items[i].tags = m.cfg.Tags(m.bookmarkKey(key))
+		m.items[i].note = m.cfg.Note(m.bookmarkKey(key))
+	}
+
 	// Sort all items alphabetically by name
 	sort.Slice(m.items, func(i, j int) bool {
 		return m.items[i].info.Name < m.items[j].info.Name
 	})
+	m.listChanged()
+}
+
+// applyIndex fills in what the package lists don't carry from the local
+// catalog: the candidate version, and for bookmarks that aren't installed
+// the version, size and description.
+func (m *Model) applyIndex() {
+	if m.index == nil {
+		return
+	}
+	for i := range m.items {
+		info := &m.items[i].info
+		if info.Manager != m.mgr.Name() {
+			// A tool's package; the catalog is the current manager's
+			continue
+		}
+		entry, ok := m.index.Lookup(info.Name)
+		if !ok {
+			continue
+		}
+		info.CandidateVersion = entry.Version
+		if info.Version == "" {
+			info.Version = entry.Version
+			info.Description = entry.Description
+			info.InstalledSize = entry.InstalledSize
+		}
+	}
+	m.listChanged()
 }
 
-func (m *Model) updateInstallStatus(pkg string, installed bool) {
+func (m *Model) updateInstallStatus(key pkgKey, installed bool) {
 	for i := range m.items {
-		if m.items[i].info.Name == pkg {
+		if keyOf(m.items[i].info) == key {
 			m.items[i].info.Installed = installed
 			break
 		}
 	}
 	for i := range m.filtered {
-		if m.filtered[i].info.Name == pkg {
+		if keyOf(m.filtered[i].info) == key {
 			m.filtered[i].info.Installed = installed
 			break
 		}
 	}
+	m.listChanged()
 }
 
-func (m *Model) updateBookmarkStatus(pkg string, bookmarked bool) {
+func (m *Model) updateBookmarkStatus(key pkgKey, bookmarked bool) {
 	for i := range m.items {
-		if m.items[i].info.Name == pkg {
+		if keyOf(m.items[i].info) == key {
 			m.items[i].bookmarked = bookmarked
 			break
 		}
 	}
 	for i := range m.filtered {
-		if m.filtered[i].info.Name == pkg {
+		if keyOf(m.filtered[i].info) == key {
 			m.filtered[i].bookmarked = bookmarked
 			break
 		}
 	}
+	m.listChanged()
 }
 
 // mergeBookmarkedItems adds any bookmarked packages from filtered results
@@ -540,17 +1417,17 @@ func (m *Model) mergeBookmarkedItems() {
 		return
 	}
 
-	// Build set of existing item names
-	existing := make(map[string]bool)
+	// Build set of existing items
+	existing := make(map[pkgKey]bool)
 	for _, item := range m.items {
-		existing[item.info.Name] = true
+		existing[keyOf(item.info)] = true
 	}
 
 	// Add bookmarked items from filtered that don't exist in main list
 	for _, item := range m.filtered {
-		if item.bookmarked && !existing[item.info.Name] {
+		if item.bookmarked && !existing[keyOf(item.info)] {
 			m.items = append(m.items, item)
-			existing[item.info.Name] = true
+			existing[keyOf(item.info)] = true
 		}
 	}
 
@@ -558,39 +1435,106 @@ func (m *Model) mergeBookmarkedItems() {
 	sort.Slice(m.items, func(i, j int) bool {
 		return m.items[i].info.Name < m.items[j].info.Name
 	})
+	m.listChanged()
+}
+
+// visibleCache holds the last visibleItems, which filters, fuzzy-matches
+// and sorts the list and is asked for several times per key press and
+// render. The key catches changed settings; listChanged drops it when the
+// lists themselves change.
+type visibleCache struct {
+	key   visibleKey
+	items []packageItem
+	ok    bool
+}
+
+// visibleKey is what visibleItems depends on besides the lists
+type visibleKey struct {
+	results     bool // showing search results
+	filters     searchFilters
+	viewFilter  viewFilter
+	tagFilter   string
+	filterQuery string
+	sortOrder   sortOrder
+}
+
+// listChanged drops the cached visible items, for after the items or
+// search results were replaced or edited in place
+func (m *Model) listChanged() {
+	if m.visible != nil {
+		m.visible.ok = false
+	}
 }
 
 func (m Model) visibleItems() []packageItem {
+	key := visibleKey{
+		results:     m.filtered != nil,
+		filters:     m.searchFilters,
+		viewFilter:  m.viewFilter,
+		tagFilter:   m.tagFilter,
+		filterQuery: m.filterQuery,
+		sortOrder:   m.sortOrder,
+	}
+	if m.visible == nil {
+		return m.filterItems()
+	}
+	if !m.visible.ok || m.visible.key != key {
+		*m.visible = visibleCache{key: key, items: m.filterItems(), ok: true}
+	}
+	return m.visible.items
+}
+
+// filterItems works out the list as shown: the search results through the
+// result filters, or the items through the view, tag and fuzzy filters,
+// then sorted
+func (m Model) filterItems() []packageItem {
 	if m.filtered != nil {
-		return m.filtered
+		return sortItems(m.searchFilters.apply(m.filtered), m.sortOrder)
 	}
+
+	var visible []packageItem
 	switch m.viewFilter {
 	case filterBookmarked:
-		var visible []packageItem
 		for _, item := range m.items {
 			if item.bookmarked {
 				visible = append(visible, item)
 			}
 		}
-		return visible
 	case filterManual:
-		var visible []packageItem
 		for _, item := range m.items {
-			if item.bookmarked || m.manualSet[item.info.Name] {
+			if item.bookmarked || m.manualSet[keyOf(item.info)] {
 				visible = append(visible, item)
 			}
 		}
-		return visible
 	default:
-		return m.items
+		visible = m.items
 	}
+
+	if m.tagFilter != "" {
+		var tagged []packageItem
+		for _, item := range visible {
+			if m.hasTag(item) {
+				tagged = append(tagged, item)
+			}
+		}
+		visible = tagged
+	}
+
+	if m.filterQuery != "" {
+		visible = fuzzyFilter(visible, m.filterQuery)
+	}
+	return sortItems(visible, m.sortOrder)
 }
 
 // maxVisibleItems returns how many package items can fit on screen
 // accounting for header, search bar, status, and help lines
 func (m Model) maxVisibleItems() int {
-	// Header (2) + search bar (2) + section header (1) + help (3) + status (1) = 9, use 10 for safety
+	// Header (2) + search bar (2) + section header (1) + column header (1) +
+	// help (2) + status (1) = 9, use 10 for safety
 	overhead := 10
+	if m.filtered != nil {
+		overhead++ // search filter help line
+	}
 	available := m.height - overhead
 	if available < 1 {
 		return 1
@@ -598,6 +1542,13 @@ func (m Model) maxVisibleItems() int {
 	return available
 }
 
+// moveCursor moves the cursor by delta rows, clamped to the list
+func (m *Model) moveCursor(delta int) {
+	last := len(m.visibleItems()) - 1
+	m.cursor = max(min(m.cursor+delta, last), 0)
+	m.ensureCursorVisible()
+}
+
 // ensureCursorVisible adjusts scroll to keep cursor in view
 func (m *Model) ensureCursorVisible() {
 	maxVisible := m.maxVisibleItems()
@@ -623,8 +1574,11 @@ func (m Model) View() string {
 	// Header
 	header := lipgloss.JoinHorizontal(lipgloss.Top,
 		titleStyle.Render("boxy"),
-		managerStyle.Render(fmt.Sprintf("[%s]", m.mgr.Name())),
+		managerStyle.Render(fmt.Sprintf("[%s]", m.managerLabel())),
 	)
+	if m.refreshing {
+		header += dimStyle.Render("refreshing...")
+	}
 	b.WriteString(header)
 	b.WriteString("\n")
 	b.WriteString(strings.Repeat("─", min(m.width, 60)))
@@ -632,8 +1586,20 @@ func (m Model) View() string {
 
 	// Search bar
 	if m.viewMode == viewSearch {
-		b.WriteString(searchStyle.Render("Search: "))
-		b.WriteString(m.searchInput.View())
+		if m.searchRemote {
+			b.WriteString(searchStyle.Render("Search: "))
+			b.WriteString(m.searchInput.View())
+			b.WriteString(dimStyle.Render("  (/ filter local)"))
+		} else {
+			b.WriteString(searchStyle.Render("Filter: "))
+			b.WriteString(m.searchInput.View())
+			b.WriteString(dimStyle.Render("  (/ search remote)"))
+		}
+		b.WriteString("\n")
+	} else if m.filterQuery != "" {
+		b.WriteString(searchStyle.Render("Filter: "))
+		b.WriteString(m.filterQuery)
+		b.WriteString(dimStyle.Render("  (/ to edit, Esc to clear)"))
 		b.WriteString("\n")
 	} else if m.filtered != nil {
 		b.WriteString(searchStyle.Render("Search: "))
@@ -650,30 +1616,28 @@ func (m Model) View() string {
 	items := m.visibleItems()
 	maxVisible := m.maxVisibleItems()
 
-	if m.searching {
+	if m.searching && m.filtered == nil {
 		b.WriteString(headerStyle.Render("SEARCH RESULTS"))
 		b.WriteString("\n")
-		b.WriteString(dimStyle.Render("  Searching..."))
+		b.WriteString("  " + m.spinner.View() + dimStyle.Render(" Searching..."))
 		b.WriteString("\n")
 	} else if m.filtered != nil {
+		// Earlier results stay visible while the next query is in flight
 		b.WriteString(headerStyle.Render("SEARCH RESULTS"))
+		if labels := m.searchFilters.labels(); len(labels) > 0 {
+			b.WriteString(searchStyle.Render(" [" + strings.Join(labels, ", ") + "]"))
+		}
+		b.WriteString(m.sortLabel())
+		if m.searching {
+			b.WriteString(" " + m.spinner.View())
+		}
 		if len(items) > maxVisible {
 			b.WriteString(dimStyle.Render(fmt.Sprintf(" (%d-%d of %d)", m.scroll+1, min(m.scroll+maxVisible, len(items)), len(items))))
 		}
 		b.WriteString("\n")
 		m.renderItemsViewport(&b, items, 0, m.scroll, maxVisible)
 	} else {
-		switch m.viewFilter {
-		case filterBookmarked:
-			b.WriteString(headerStyle.Render("PACKAGES (bookmarked)"))
-		case filterManual:
-			b.WriteString(headerStyle.Render("PACKAGES (manual)"))
-		default:
-			b.WriteString(headerStyle.Render("PACKAGES (all)"))
-		}
-		if len(items) > maxVisible {
-			b.WriteString(dimStyle.Render(fmt.Sprintf(" (%d-%d of %d)", m.scroll+1, min(m.scroll+maxVisible, len(items)), len(items))))
-		}
+		b.WriteString(m.listHeader(len(items)))
 		b.WriteString("\n")
 		m.renderItemsViewport(&b, items, 0, m.scroll, maxVisible)
 	}
@@ -681,11 +1645,7 @@ func (m Model) View() string {
 	// Status message
 	if m.installing {
 		b.WriteString("\n")
-		action := "Installing"
-		if m.confirmAct == confirmUninstall {
-			action = "Uninstalling"
-		}
-		b.WriteString(searchStyle.Render(fmt.Sprintf("%s %s...", action, m.confirmPkg)))
+		b.WriteString(searchStyle.Render(m.confirmAct.progress() + " " + m.confirmTarget() + "..."))
 	}
 	if m.statusMsg != "" {
 		b.WriteString("\n")
@@ -697,28 +1657,77 @@ func (m Model) View() string {
 	}
 
 	// Help
+	m.help.Width = m.listWidth()
 	b.WriteString("\n")
-	b.WriteString(helpStyle.Render("↑/k up  ↓/j down  i install  u uninstall  b bookmark"))
-	b.WriteString("\n")
-	b.WriteString(helpStyle.Render("Enter info  / search  v view  q quit"))
+	b.WriteString(m.help.View(m.helpKeys()))
+	if m.filtered != nil && m.viewMode == viewNormal {
+		b.WriteString("\n")
+		b.WriteString(m.help.ShortHelpView(m.resultFilterKeys()))
+	}
+
+	screen := b.String()
+	if m.splitPane() {
+		screen = m.joinDetailPanel(screen)
+	}
 
 	// Modal overlay
 	if m.viewMode == viewInfo {
-		return m.renderWithModal(b.String(), "Package Info", m.infoText)
+		return m.renderInfoModal(screen)
+	}
+	if m.viewMode == viewHelp {
+		return m.renderHelpOverlay(screen)
+	}
+	if m.viewMode == viewPalette {
+		return m.renderPalette(screen)
+	}
+	if m.viewMode == viewSnapshots {
+		return m.renderSnapshots(screen)
+	}
+	if m.viewMode == viewTags {
+		msg := fmt.Sprintf("Tags for %s, separated by commas:\n\n%s", m.tagsPkg.name, m.tagInput.View())
+		return m.renderWithModal(screen, "Tags", msg)
+	}
+	if m.viewMode == viewNote {
+		msg := fmt.Sprintf("Note for %s:\n\n%s", m.notePkg.name, m.noteInput.View())
+		return m.renderWithModal(screen, "Note", msg)
 	}
 	if m.viewMode == viewConfirm {
-		action := "install"
-		if m.confirmAct == confirmUninstall {
-			action = "uninstall"
+		target := m.confirmTarget()
+		if detail := m.confirmDetail(); detail != "" {
+			target += " (" + detail + ")"
 		}
-		msg := fmt.Sprintf("Are you sure you want to %s %s?\n\n[y] Yes  [n] No", action, m.confirmPkg)
-		return m.renderWithModal(b.String(), "Confirm", msg)
+		yes, no := m.confirmButtons()
+		msg := fmt.Sprintf("Are you sure you want to %s %s?\n\n%s  %s", m.confirmAct.verb(), target, yes, no)
+		return m.renderWithModal(screen, "Confirm", msg)
 	}
 	if m.viewMode == viewSudoPassword {
-		msg := fmt.Sprintf("sudo password for %s:\n\n%s\n\nEnter to submit, Esc to cancel", m.confirmPkg, m.passwordInput.View())
-		return m.renderWithModal(b.String(), "Authentication", msg)
+		msg := fmt.Sprintf("sudo password for %s:\n\n%s", m.confirmTarget(), m.passwordInput.View())
+		return m.renderWithModal(screen, "Authentication", msg)
 	}
 
+	return screen
+}
+
+// listHeader is the section header above the package list: the view, the
+// sort order, the scroll position and the tag strip
+func (m Model) listHeader(count int) string {
+	var b strings.Builder
+	switch m.viewFilter {
+	case filterBookmarked:
+		b.WriteString(headerStyle.Render("PACKAGES (bookmarked)"))
+	case filterManual:
+		b.WriteString(headerStyle.Render("PACKAGES (manual)"))
+	default:
+		b.WriteString(headerStyle.Render("PACKAGES (all)"))
+	}
+	b.WriteString(m.sortLabel())
+	maxVisible := m.maxVisibleItems()
+	if count > maxVisible {
+		b.WriteString(dimStyle.Render(fmt.Sprintf(" (%d-%d of %d)", m.scroll+1, min(m.scroll+maxVisible, count), count)))
+	} else if m.filterQuery != "" {
+		b.WriteString(dimStyle.Render(fmt.Sprintf(" (%d matches)", count)))
+	}
+	b.WriteString(m.renderTagStrip())
 	return b.String()
 }
 
@@ -728,6 +1737,17 @@ func (m Model) View() string {
 // maxItems: maximum items to render
 // returns: number of items rendered
 func (m Model) renderItemsViewport(b *strings.Builder, items []packageItem, offset, scroll, maxItems int) int {
+	// Size the columns to the entries on screen
+	var onScreen []packageItem
+	for i := scroll - offset; i < len(items) && i < scroll-offset+maxItems; i++ {
+		if i >= 0 {
+			onScreen = append(onScreen, items[i])
+		}
+	}
+	ids, widths := m.layoutColumns(onScreen)
+	b.WriteString(m.renderColumnHeader(ids, widths))
+	b.WriteString("\n")
+
 	rendered := 0
 	for i, item := range items {
 		globalIdx := offset + i
@@ -752,28 +1772,29 @@ func (m Model) renderItemsViewport(b *strings.Builder, items []packageItem, offs
 			bullet = bookmarkStyle.Render("●")
 		}
 
-		name := item.info.Name
+		nameStyle := normalStyle
 		if globalIdx == m.cursor {
-			name = selectedStyle.Render(name)
-		} else {
-			name = normalStyle.Render(name)
+			nameStyle = selectedStyle
 		}
 
-		desc := item.info.Description
-		if len(desc) > 30 {
-			desc = desc[:27] + "..."
-		}
-		if desc == "" {
-			desc = "-"
+		cells := make([]string, len(ids))
+		for c, id := range ids {
+			style := dimStyle
+			if id == "name" {
+				style = nameStyle
+			}
+			cells[c] = renderCell(columns[id], item, widths[c], style)
 		}
-		desc = dimStyle.Render(desc)
 
 		status := notInstalledStyle.Render("[ ]")
 		if item.info.Installed {
 			status = installedStyle.Render("[✓]")
 		}
+		if item.note != "" {
+			status += bookmarkStyle.Render(" ✎")
+		}
 
-		line := fmt.Sprintf("%s%s %s  %s  %s", prefix, bullet, name, desc, status)
+		line := fmt.Sprintf("%s%s %s  %s", prefix, bullet, strings.Join(cells, strings.Repeat(" ", columnGap)), status)
 		b.WriteString(line)
 		b.WriteString("\n")
 		rendered++
@@ -781,13 +1802,124 @@ func (m Model) renderItemsViewport(b *strings.Builder, items []packageItem, offs
 	return rendered
 }
 
+// renderMatches truncates and pads s to width and renders it in base, with
+// the runes at the fuzzy match positions highlighted
+func renderMatches(s string, width int, positions []int, base lipgloss.Style) string {
+	text := fmt.Sprintf("%-*s", width, truncate(s, width))
+	if len(positions) == 0 {
+		return base.Render(text)
+	}
+
+	// Positions past a truncation point would land on the "..."
+	limit := len(s)
+	if len(s) > width {
+		limit = width - 3
+	}
+	matched := make(map[int]bool, len(positions))
+	for _, pos := range positions {
+		if pos < limit {
+			matched[pos] = true
+		}
+	}
+
+	// Render runs of matched and unmatched runes to keep escape codes short
+	var b strings.Builder
+	runes := []rune(text)
+	for start := 0; start < len(runes); {
+		end := start
+		for end < len(runes) && matched[end] == matched[start] {
+			end++
+		}
+		style := base
+		if matched[start] {
+			style = matchStyle
+		}
+		b.WriteString(style.Render(string(runes[start:end])))
+		start = end
+	}
+	return b.String()
+}
+
+// truncate shortens s to at most width characters, marking the cut with "..."
+func truncate(s string, width int) string {
+	if len(s) <= width {
+		return s
+	}
+	if width <= 3 {
+		return s[:width]
+	}
+	return s[:width-3] + "..."
+}
+
 func (m Model) renderWithModal(bg, title, content string) string {
+	return m.overlayModal(bg, title, content, plainHelp(m.helpKeys().ShortHelp()...))
+}
+
+// renderInfoModal renders the info text (or the file list) in a modal that
+// scrolls when the content is taller than the terminal
+func (m Model) renderInfoModal(bg string) string {
+	lines := m.infoLines()
+	page := m.infoPageSize()
+	start := min(m.infoScroll, max(len(lines)-page, 0))
+	end := min(start+page, len(lines))
+
+	title := "Package Info"
+	if m.infoTitle != "" {
+		title = m.infoTitle
+	}
+	footer := plainHelp(m.helpKeys().ShortHelp()...)
+	if m.showFiles {
+		title = fmt.Sprintf("Files: %s", m.infoPkg)
+		if m.filesLoaded && m.filesErr == nil {
+			title = fmt.Sprintf("Files: %s (%d)", m.infoPkg, len(m.infoFiles))
+		}
+	}
+	if len(lines) > page {
+		footer = fmt.Sprintf("(%d-%d of %d)  %s", start+1, end, len(lines), footer)
+	}
+	return m.overlayModal(bg, title, strings.Join(lines[start:end], "\n"), footer)
+}
+
+// infoLines returns the modal content wrapped to the modal's content width
+func (m Model) infoLines() []string {
+	text := m.infoText
+	if m.showFiles {
+		switch {
+		case m.filesErr != nil:
+			text = fmt.Sprintf("Error listing files: %v", m.filesErr)
+		case !m.filesLoaded:
+			text = "Loading files..."
+		case len(m.infoFiles) == 0:
+			text = "No files"
+		default:
+			text = strings.Join(m.infoFiles, "\n")
+		}
+	}
+	wrapped := lipgloss.NewStyle().Width(modalContentWidth).Render(text)
+	return strings.Split(wrapped, "\n")
+}
+
+// infoPageSize returns how many lines of info text fit in the modal
+func (m Model) infoPageSize() int {
+	// Border (2) + padding (2) + title (2) + footer (2) + margin (2) = 10
+	available := m.height - 10
+	if available < 3 {
+		return 3
+	}
+	return available
+}
+
+func (m Model) overlayModal(bg, title, content, footer string) string {
 	lines := strings.Split(bg, "\n")
+	// Pad short screens so tall modals aren't clipped by the background
+	for len(lines) < m.height {
+		lines = append(lines, "")
+	}
 
 	modalContent := fmt.Sprintf("%s\n\n%s\n\n%s",
 		titleStyle.Render(title),
 		content,
-		dimStyle.Render("Press Esc to close"),
+		dimStyle.Render(footer),
 	)
 	modal := modalStyle.Render(modalContent)
 	modalLines := strings.Split(modal, "\n")
@@ -807,26 +1939,108 @@ func (m Model) renderWithModal(bg, title, content string) string {
 	return strings.Join(lines, "\n")
 }
 
-func formatInfo(info manager.PackageInfo) string {
+func formatInfo(info manager.PackageInfo, note string) string {
 	var b strings.Builder
 	b.WriteString(fmt.Sprintf("Name: %s\n", info.Name))
-	if info.Version != "" {
+	if info.InstalledVersion != "" || info.CandidateVersion != "" {
+		if info.InstalledVersion != "" {
+			b.WriteString(fmt.Sprintf("Installed: %s\n", info.InstalledVersion))
+		}
+		if info.CandidateVersion != "" {
+			candidate := info.CandidateVersion
+			if info.InstalledVersion != "" && candidate != info.InstalledVersion {
+				candidate += " (upgrade available)"
+			}
+			b.WriteString(fmt.Sprintf("Candidate: %s\n", candidate))
+		}
+	} else if info.Version != "" {
 		b.WriteString(fmt.Sprintf("Version: %s\n", info.Version))
 	}
 	if info.Description != "" {
 		b.WriteString(fmt.Sprintf("Description: %s\n", info.Description))
 	}
+	writeField(&b, "Section", info.Section)
+	writeField(&b, "Priority", info.Priority)
+	if info.InstalledSize > 0 {
+		writeField(&b, "Installed-Size", formatSize(info.InstalledSize))
+	}
+	writeField(&b, "Maintainer", info.Maintainer)
+	writeField(&b, "Homepage", info.Homepage)
+	writeField(&b, "License", info.License)
+	writeField(&b, "Source", info.Source)
+	writeField(&b, "Depends", strings.Join(info.Depends, ", "))
+	writeField(&b, "Build-Depends", strings.Join(info.BuildDepends, ", "))
+	writeField(&b, "Recommends", strings.Join(info.Recommends, ", "))
+	writeField(&b, "Suggests", strings.Join(info.Suggests, ", "))
+	if len(info.InstalledVersions) > 1 {
+		writeField(&b, "Installed versions", strings.Join(info.InstalledVersions, ", "))
+	}
+	if !info.InstallTime.IsZero() {
+		writeField(&b, "Installed on", info.InstallTime.Format("2006-01-02 15:04"))
+	}
 	status := "Not installed"
 	if info.Installed {
 		status = "Installed"
+		if len(info.InstalledVersions) > 0 {
+			// Only brew reports how a package was installed
+			if info.InstalledOnRequest {
+				status += ", on request"
+			} else {
+				status += ", as a dependency"
+			}
+			if info.PouredFromBottle {
+				status += ", from bottle"
+			} else {
+				status += ", built from source"
+			}
+		}
 	}
 	b.WriteString(fmt.Sprintf("Status: %s", status))
+	if note != "" {
+		b.WriteString("\n\nNote:\n")
+		b.WriteString(note)
+	}
+	if info.LongDescription != "" {
+		b.WriteString("\n\n")
+		b.WriteString(info.LongDescription)
+	}
+	if info.Caveats != "" {
+		b.WriteString("\n\nCaveats:\n")
+		b.WriteString(info.Caveats)
+	}
 	return b.String()
 }
 
+func writeField(b *strings.Builder, label, value string) {
+	if value != "" {
+		b.WriteString(fmt.Sprintf("%s: %s\n", label, value))
+	}
+}
+
+// formatSize renders a byte count in human-readable binary units
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
 	}
 	return b
 }
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,266 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// column describes one column of the package list
+type column struct {
+	title string
+	min   int  // narrowest the column may shrink to
+	max   int  // widest the column grows to fit its content
+	flex  bool // takes whatever width is left over
+	right bool // right-aligned (numbers)
+	value func(item packageItem) string
+	match func(item packageItem) []int // fuzzy match positions to highlight
+}
+
+// columns are the available list columns, keyed by their config name
+var columns = map[string]column{
+	"name": {
+		title: "NAME", min: 12, max: 30,
+		value: func(item packageItem) string { return item.info.Name },
+		match: func(item packageItem) []int { return item.nameMatch },
+	},
+	"version": {
+		title: "VERSION", min: 8, max: 20,
+		value: func(item packageItem) string { return item.info.Version },
+	},
+	"candidate": {
+		title: "CANDIDATE", min: 9, max: 20,
+		value: func(item packageItem) string { return item.info.CandidateVersion },
+	},
+	"description": {
+		title: "DESCRIPTION", min: 10, flex: true,
+		value: func(item packageItem) string { return item.info.Description },
+		match: func(item packageItem) []int { return item.descMatch },
+	},
+	"size": {
+		title: "SIZE", min: 9, max: 9, right: true,
+		value: func(item packageItem) string {
+			if item.info.InstalledSize <= 0 {
+				return ""
+			}
+			return formatSize(item.info.InstalledSize)
+		},
+	},
+	"manager": {
+		title: "MANAGER", min: 7, max: 10,
+		value: func(item packageItem) string { return item.info.Manager },
+	},
+	"install_date": {
+		title: "INSTALLED", min: 10, max: 10,
+		value: func(item packageItem) string {
+			if item.info.InstallTime.IsZero() {
+				return ""
+			}
+			return item.info.InstallTime.Format("2006-01-02")
+		},
+	},
+	"tags": {
+		title: "TAGS", min: 4, max: 20,
+		value: func(item packageItem) string { return strings.Join(item.tags, ",") },
+	},
+	"note": {
+		title: "NOTE", min: 4, max: 30,
+		value: func(item packageItem) string {
+			line, _, _ := strings.Cut(item.note, "\n")
+			return line
+		},
+	},
+}
+
+var defaultColumns = []string{"name", "version", "description"}
+
+// configuredColumns validates the column names from the config, dropping
+// unknown and duplicate names. The name column is always shown.
+func configuredColumns(names []string) []string {
+	seen := make(map[string]bool)
+	var valid []string
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := columns[name]; ok && !seen[name] {
+			seen[name] = true
+			valid = append(valid, name)
+		}
+	}
+	if len(valid) == 0 {
+		return defaultColumns
+	}
+	if !seen["name"] {
+		valid = append([]string{"name"}, valid...)
+	}
+	return valid
+}
+
+// Row layout around the columns: "> " + "● " before, "  [✓]" and the note
+// mark " ✎" after
+const (
+	rowPrefixWidth = 4
+	rowStatusWidth = 7
+	columnGap      = 2
+)
+
+// layoutColumns picks a width for each configured column so the rows fit
+// the terminal. Columns size to their content within [min, max]; if that
+// is too wide they shrink to their minimum from the right, then drop from
+// the right (the description last, the name never). The flexible column
+// (description) gets what is left.
+func (m Model) layoutColumns(rows []packageItem) ([]string, []int) {
+	available := m.listWidth() - rowPrefixWidth - rowStatusWidth
+
+	ids := append([]string(nil), m.columns...)
+	widths := make([]int, len(ids))
+	for i, id := range ids {
+		col := columns[id]
+		if col.flex {
+			widths[i] = col.min
+			continue
+		}
+		w := len(col.title)
+		for _, item := range rows {
+			w = max(w, len(col.value(item)))
+		}
+		widths[i] = max(col.min, min(w, col.max))
+	}
+
+	total := func() int {
+		sum := 0
+		for _, w := range widths {
+			sum += w
+		}
+		return sum + columnGap*(len(widths)-1)
+	}
+
+	for i := len(ids) - 1; i >= 0 && total() > available; i-- {
+		widths[i] = columns[ids[i]].min
+	}
+	// Drop fixed columns first, keeping the name and description longest
+	for _, keepFlex := range []bool{true, false} {
+		for i := len(ids) - 1; i >= 0 && total() > available; i-- {
+			if ids[i] == "name" || keepFlex && columns[ids[i]].flex {
+				continue
+			}
+			ids = append(ids[:i:i], ids[i+1:]...)
+			widths = append(widths[:i:i], widths[i+1:]...)
+		}
+	}
+
+	for i, id := range ids {
+		if columns[id].flex {
+			widths[i] += max(available-total(), 0)
+		}
+	}
+	return ids, widths
+}
+
+// listWidth is the width the package list renders into
+func (m Model) listWidth() int {
+	if m.width <= 0 {
+		return 80
+	}
+	if m.splitPane() {
+		return m.width - m.detailWidth()
+	}
+	return m.width
+}
+
+// renderColumnHeader renders the column titles, marking the sort column
+func (m Model) renderColumnHeader(ids []string, widths []int) string {
+	sortColumn := map[sortOrder]string{
+		sortSize:        "size",
+		sortInstallDate: "install_date",
+	}[m.sortOrder]
+
+	cells := make([]string, len(ids))
+	for i, id := range ids {
+		col := columns[id]
+		title := col.title
+		if id == sortColumn {
+			title += "▼"
+		}
+		cells[i] = padCell(title, widths[i], col.right)
+	}
+	return dimStyle.Render(strings.Repeat(" ", rowPrefixWidth) + strings.Join(cells, strings.Repeat(" ", columnGap)))
+}
+
+// renderCell renders one column of a row
+func renderCell(col column, item packageItem, width int, base lipgloss.Style) string {
+	value := col.value(item)
+	if value == "" {
+		value = "-"
+	}
+	if col.right {
+		return base.Render(padCell(value, width, true))
+	}
+	var positions []int
+	if col.match != nil {
+		positions = col.match(item)
+	}
+	return renderMatches(value, width, positions, base)
+}
+
+func padCell(s string, width int, right bool) string {
+	s = truncate(s, width)
+	if right {
+		return fmt.Sprintf("%*s", width, s)
+	}
+	return fmt.Sprintf("%-*s", width, s)
+}
+
+type sortOrder int
+
+const (
+	sortName sortOrder = iota
+	sortSize
+	sortInstallDate
+	sortUpdated
+	numSortOrders
+)
+
+func (s sortOrder) String() string {
+	switch s {
+	case sortSize:
+		return "size"
+	case sortInstallDate:
+		return "install date"
+	case sortUpdated:
+		return "recently updated"
+	default:
+		return "name"
+	}
+}
+
+// sortItems returns items ordered by the sort order, largest or newest
+// first. Sorting by name keeps the list's own order, which is already
+// alphabetical for packages and by relevance for search and filter results.
+func sortItems(items []packageItem, order sortOrder) []packageItem {
+	if order == sortName {
+		return items
+	}
+	sorted := append([]packageItem(nil), items...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].info, sorted[j].info
+		switch order {
+		case sortSize:
+			return a.InstalledSize > b.InstalledSize
+		case sortInstallDate:
+			return a.InstallTime.After(b.InstallTime)
+		default:
+			return a.UpdateTime.After(b.UpdateTime)
+		}
+	})
+	return sorted
+}
+
+// sortLabel is shown next to the list header when not sorted by name
+func (m Model) sortLabel() string {
+	if m.sortOrder == sortName {
+		return ""
+	}
+	return dimStyle.Render(" sorted by " + m.sortOrder.String())
+}
@@ -0,0 +1,109 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Terminals at least splitPaneMinWidth wide show a detail panel beside the
+// list that follows the cursor. Narrower ones only have the info modal.
+const (
+	splitPaneMinWidth = 120
+	detailDebounce    = 150 * time.Millisecond
+)
+
+func (m Model) splitPane() bool {
+	return m.width >= splitPaneMinWidth
+}
+
+// detailWidth is the width of the detail panel, border included
+func (m Model) detailWidth() int {
+	return m.width * 2 / 5
+}
+
+// followCursor points the detail panel at the package under the cursor.
+// Info that was already fetched shows immediately; otherwise the fetch
+// waits out detailDebounce so scrolling through the list doesn't run a
+// GetInfo per row.
+func (m *Model) followCursor() tea.Cmd {
+	if !m.splitPane() {
+		return nil
+	}
+	pkg := ""
+	if items := m.visibleItems(); m.cursor < len(items) {
+		pkg = items[m.cursor].info.Name
+	}
+	if pkg == m.detailPkg {
+		return nil
+	}
+	m.detailPkg = pkg
+	m.detailErr = nil
+	m.detailGen++
+	if _, ok := m.details[pkg]; ok || pkg == "" {
+		return nil
+	}
+	gen := m.detailGen
+	return tea.Tick(detailDebounce, func(time.Time) tea.Msg {
+		return detailDebounceMsg{gen: gen}
+	})
+}
+
+// forgetDetail drops the fetched info for pkg after it was installed or
+// removed, refetching it if the panel is showing it
+func (m *Model) forgetDetail(pkg string) tea.Cmd {
+	delete(m.details, pkg)
+	if pkg != m.detailPkg || !m.splitPane() {
+		return nil
+	}
+	m.detailGen++
+	return m.fetchDetail(pkg)
+}
+
+func (m Model) fetchDetail(pkg string) tea.Cmd {
+	mgr := m.managerOf(pkg)
+	return func() tea.Msg {
+		info, err := mgr.GetInfo(context.Background(), pkg)
+		return packageDetailMsg{pkg: pkg, info: info, err: err}
+	}
+}
+
+// joinDetailPanel places the detail panel to the right of the screen
+func (m Model) joinDetailPanel(screen string) string {
+	left := lipgloss.NewStyle().Width(m.listWidth()).Render(screen)
+	return lipgloss.JoinHorizontal(lipgloss.Top, left, m.renderDetailPanel(m.height-1))
+}
+
+func (m Model) renderDetailPanel(height int) string {
+	width := m.detailWidth()
+	// Border (2) + padding (2)
+	contentWidth := width - 4
+	contentHeight := max(height-2, 1)
+
+	var text string
+	info, ok := m.details[m.detailPkg]
+	switch {
+	case m.detailPkg == "":
+		text = dimStyle.Render("No package selected")
+	case m.detailErr != nil:
+		text = errorStyle.Render(fmt.Sprintf("Error loading info: %v", m.detailErr))
+	case ok:
+		text = formatInfo(info, m.cfg.Note(m.bookmarkManager(m.managerOf(m.detailPkg).Name()), m.detailPkg))
+	default:
+		text = dimStyle.Render(fmt.Sprintf("Loading %s...", m.detailPkg))
+	}
+
+	wrapped := lipgloss.NewStyle().Width(contentWidth).Render(text)
+	lines := strings.Split(wrapped, "\n")
+	if len(lines) > contentHeight {
+		lines = lines[:contentHeight]
+	}
+	return detailStyle.
+		Width(width - 2).
+		Height(contentHeight).
+		Render(strings.Join(lines, "\n"))
+}
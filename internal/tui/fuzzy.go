@@ -0,0 +1,124 @@
+package tui
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Fuzzy matching scores, loosely modelled on fzf: every matched character
+// scores, runs of consecutive matches and matches at word boundaries score
+// extra, and gaps between matches cost a little.
+const (
+	scoreMatch       = 16
+	bonusConsecutive = 8
+	bonusBoundary    = 10
+	penaltyGap       = 1
+	// Name matches outrank description matches of the same quality
+	nameWeight = 2
+)
+
+// fuzzyMatch reports whether pattern's runes appear in order in text
+// (case-insensitively), with a score and the matched rune positions. After
+// finding the leftmost match it scans backwards from the end of the match
+// to tighten it, so "gc" in "go-git-clone" highlights "git-clone"'s g and c
+// rather than the first g.
+func fuzzyMatch(text, pattern string) (int, []int, bool) {
+	t := []rune(strings.ToLower(text))
+	p := []rune(strings.ToLower(pattern))
+	if len(p) == 0 {
+		return 0, nil, true
+	}
+
+	// Forward pass: find where the first complete match ends
+	pi, end := 0, -1
+	for ti := 0; ti < len(t); ti++ {
+		if t[ti] == p[pi] {
+			pi++
+			if pi == len(p) {
+				end = ti
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+
+	// Backward pass: find the latest start that still matches
+	positions := make([]int, len(p))
+	pi = len(p) - 1
+	for ti := end; ti >= 0 && pi >= 0; ti-- {
+		if t[ti] == p[pi] {
+			positions[pi] = ti
+			pi--
+		}
+	}
+
+	score := 0
+	for i, pos := range positions {
+		score += scoreMatch
+		if pos == 0 || !unicode.IsLetter(t[pos-1]) && !unicode.IsDigit(t[pos-1]) {
+			score += bonusBoundary
+		}
+		if i > 0 {
+			if pos == positions[i-1]+1 {
+				score += bonusConsecutive
+			} else {
+				score -= penaltyGap * (pos - positions[i-1] - 1)
+			}
+		}
+	}
+	return score, positions, true
+}
+
+// fuzzyFilter keeps the items matching every space-separated term of query
+// in their name or description, best score first. Matched positions are
+// recorded on the returned items for highlighting.
+func fuzzyFilter(items []packageItem, query string) []packageItem {
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return items
+	}
+
+	type scored struct {
+		item  packageItem
+		score int
+	}
+	var matches []scored
+	for _, item := range items {
+		total := 0
+		item.nameMatch, item.descMatch = nil, nil
+		ok := true
+		for _, term := range terms {
+			if score, pos, found := fuzzyMatch(item.info.Name, term); found {
+				total += score * nameWeight
+				item.nameMatch = append(item.nameMatch, pos...)
+				continue
+			}
+			if score, pos, found := fuzzyMatch(item.info.Description, term); found {
+				total += score
+				item.descMatch = append(item.descMatch, pos...)
+				continue
+			}
+			ok = false
+			break
+		}
+		if ok {
+			matches = append(matches, scored{item: item, score: total})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return len(matches[i].item.info.Name) < len(matches[j].item.info.Name)
+	})
+
+	filtered := make([]packageItem, len(matches))
+	for i, match := range matches {
+		filtered[i] = match.item
+	}
+	return filtered
+}
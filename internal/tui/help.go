@@ -0,0 +1,182 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// helpKeys is the help.KeyMap for one view mode
+type helpKeys struct {
+	short []key.Binding
+	full  [][]key.Binding
+}
+
+func (h helpKeys) ShortHelp() []key.Binding  { return h.short }
+func (h helpKeys) FullHelp() [][]key.Binding { return h.full }
+
+func newHelp() help.Model {
+	h := help.New()
+	h.ShortSeparator = "  "
+	h.Styles.ShortKey = helpKeyStyle
+	h.Styles.ShortDesc = helpStyle
+	h.Styles.ShortSeparator = helpStyle
+	h.Styles.FullKey = helpKeyStyle
+	h.Styles.FullDesc = helpStyle
+	h.Styles.FullSeparator = helpStyle
+	h.Styles.Ellipsis = helpStyle
+	return h
+}
+
+// helpKeys returns the bindings that do something in the current view mode
+func (m Model) helpKeys() helpKeys {
+	k := m.keys
+	switch m.viewMode {
+	case viewSearch:
+		toggle, enter := "search remote", "keep filter"
+		if m.searchRemote {
+			toggle, enter = "filter local", "search"
+		}
+		keys := []key.Binding{
+			navBinding(k.Up, "up"),
+			navBinding(k.Down, "down"),
+			navBinding(k.Submit, enter),
+			relabel(k.Search, toggle),
+			navBinding(k.Escape, "clear"),
+		}
+		return helpKeys{short: keys, full: [][]key.Binding{keys}}
+
+	case viewInfo:
+		files := relabel(k.Files, "files")
+		if m.showFiles {
+			files = relabel(k.Files, "info")
+		}
+		if m.infoPkg == "" {
+			// The log has no files
+			files.SetEnabled(false)
+		}
+		return helpKeys{
+			short: []key.Binding{k.Up, k.Down, files, k.Escape},
+			full: [][]key.Binding{
+				{k.Up, k.Down, k.PageUp, k.PageDown, k.Top, k.Bottom},
+				{files, k.Escape},
+			},
+		}
+
+	case viewConfirm:
+		keys := []key.Binding{k.Confirm, relabel(k.Cancel, "no")}
+		return helpKeys{short: keys, full: [][]key.Binding{keys}}
+
+	case viewSudoPassword:
+		keys := []key.Binding{navBinding(k.Submit, "submit"), navBinding(k.Escape, "cancel")}
+		return helpKeys{short: keys, full: [][]key.Binding{keys}}
+
+	case viewNote:
+		keys := []key.Binding{k.Save, navBinding(k.Escape, "cancel")}
+		return helpKeys{short: keys, full: [][]key.Binding{keys}}
+
+	case viewTags:
+		keys := []key.Binding{navBinding(k.Submit, "save"), navBinding(k.Escape, "cancel")}
+		return helpKeys{short: keys, full: [][]key.Binding{keys}}
+
+	case viewSnapshots:
+		keys := []key.Binding{k.Up, k.Down, relabel(k.Info, "restore"), relabel(k.Save, "save current"), k.Escape}
+		return helpKeys{short: keys, full: [][]key.Binding{keys}}
+
+	case viewPalette:
+		keys := []key.Binding{
+			navBinding(k.Up, "up"),
+			navBinding(k.Down, "down"),
+			navBinding(k.Submit, "run"),
+			navBinding(k.Escape, "close"),
+		}
+		return helpKeys{short: keys, full: [][]key.Binding{keys}}
+
+	case viewHelp:
+		closeKey := key.NewBinding(
+			key.WithKeys(append(k.Help.Keys(), k.Escape.Keys()...)...),
+			key.WithHelp(k.Help.Help().Key+"/"+k.Escape.Help().Key, "close"),
+		)
+		return helpKeys{short: []key.Binding{closeKey}, full: [][]key.Binding{{closeKey}}}
+	}
+
+	clear := relabel(k.Escape, "clear")
+	if m.filtered == nil && m.filterQuery == "" {
+		clear.SetEnabled(false)
+	}
+	tagFilter := k.TagFilter
+	if m.filtered != nil || len(m.cfg.AllTags()) == 0 {
+		tagFilter.SetEnabled(false)
+	}
+	full := [][]key.Binding{
+		{k.Up, k.Down, k.PageUp, k.PageDown, k.Top, k.Bottom},
+		{k.Info, k.Install, k.Uninstall, k.Upgrade, k.Undo, k.Bookmark, k.Tags, tagFilter, k.Note},
+		{k.Search, clear, k.View, k.Sort},
+	}
+	if m.filtered != nil {
+		full = append(full, m.resultFilterKeys())
+	}
+	full = append(full, []key.Binding{k.Palette, k.History, k.Snapshots, k.Help, k.Quit})
+	return helpKeys{
+		short: []key.Binding{k.Up, k.Down, k.Info, k.Install, k.Uninstall, k.Bookmark, k.Search, clear, k.Palette, k.Help, k.Quit},
+		full:  full,
+	}
+}
+
+// resultFilterKeys are the keys that filter search results
+func (m Model) resultFilterKeys() []key.Binding {
+	return []key.Binding{m.keys.FilterInstalled, m.keys.FilterNoise, m.keys.FilterManager}
+}
+
+// relabel returns a copy of b with a different help description
+func relabel(b key.Binding, desc string) key.Binding {
+	b.SetHelp(b.Help().Key, desc)
+	return b
+}
+
+// navBinding returns a copy of b for help in text-entry modes, listing only
+// the keys matchesNav accepts there
+func navBinding(b key.Binding, desc string) key.Binding {
+	var keys []string
+	for _, k := range b.Keys() {
+		if len([]rune(k)) > 1 {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		b.SetEnabled(false)
+		return b
+	}
+	b.SetKeys(keys...)
+	b.SetHelp(helpKey(keys), desc)
+	return b
+}
+
+// plainHelp renders bindings as unstyled "key desc" pairs, for modal
+// footers that are styled as a whole
+func plainHelp(bindings ...key.Binding) string {
+	var parts []string
+	for _, b := range bindings {
+		if b.Enabled() {
+			parts = append(parts, b.Help().Key+" "+b.Help().Desc)
+		}
+	}
+	return strings.Join(parts, "  ")
+}
+
+// renderHelpOverlay shows the full help for the list in a modal. Columns
+// are laid out three to a row to fit the modal width.
+func (m Model) renderHelpOverlay(bg string) string {
+	list := m
+	list.viewMode = viewNormal
+	groups := list.helpKeys().FullHelp()
+
+	h := m.help
+	h.Width = modalContentWidth
+	var rows []string
+	for i := 0; i < len(groups); i += 3 {
+		rows = append(rows, h.FullHelpView(groups[i:min(i+3, len(groups))]))
+	}
+	return m.overlayModal(bg, "Keys", strings.Join(rows, "\n\n"), plainHelp(m.helpKeys().ShortHelp()...))
+}
@@ -0,0 +1,68 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"boxy/internal/history"
+	"boxy/internal/manager"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// The history modal lists the most recent historyLimit operations, each
+// with at most historyMaxChanges changed packages
+const (
+	historyLimit      = 500
+	historyMaxChanges = 10
+)
+
+// openHistory shows boxy's operations and the managers' own logs in the
+// info modal. Reading the logs takes a moment, so it happens in the
+// background.
+func (m *Model) openHistory() tea.Cmd {
+	m.openInfo("", "Loading history...")
+	m.infoTitle = "History"
+	managers := m.managers
+	return func() tea.Msg {
+		own, err := history.Load()
+		system := make([][]history.Entry, len(managers))
+		for i, mgr := range managers {
+			system[i] = manager.SystemHistory(mgr)
+		}
+		return historyLoadedMsg{entries: history.Merge(own, system...), err: err}
+	}
+}
+
+func formatHistory(entries []history.Entry) string {
+	if len(entries) == 0 {
+		return "No changes recorded yet"
+	}
+	var b strings.Builder
+	for i, e := range entries {
+		if i == historyLimit {
+			b.WriteString(fmt.Sprintf("... and %d older\n", len(entries)-historyLimit))
+			break
+		}
+		b.WriteString(fmt.Sprintf("%s  %s %s", e.Time.Local().Format("2006-01-02 15:04"), e.Manager, e.Action))
+		if e.Source != "boxy" {
+			b.WriteString(fmt.Sprintf(" (%s log)", e.Source))
+		}
+		b.WriteString("\n")
+		for j, c := range e.Packages {
+			if j == historyMaxChanges {
+				b.WriteString(fmt.Sprintf("  ... and %d more\n", len(e.Packages)-historyMaxChanges))
+				break
+			}
+			b.WriteString("  " + c.String() + "\n")
+		}
+		if e.ChangesUnknown {
+			b.WriteString("  couldn't list the installed packages, so what changed isn't known\n")
+		}
+		if e.Failed() {
+			b.WriteString(fmt.Sprintf("  failed: %s\n", e.Error))
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
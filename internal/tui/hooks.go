@@ -0,0 +1,157 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"boxy/internal/config"
+	"boxy/internal/manager"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// hookTimeout is how long a hook may run before it's killed, so a hook
+// waiting on input can't hang boxy
+const hookTimeout = 5 * time.Minute
+
+// hook is one shell command from the config to run around an install or
+// uninstall
+type hook struct {
+	phase   string // pre_install, post_install, pre_uninstall or post_uninstall
+	pkg     string // the bookmark it's set on, or "" for a global hook
+	command string
+}
+
+func (h hook) String() string {
+	if h.pkg == "" {
+		return "global " + h.phase + " hook"
+	}
+	return fmt.Sprintf("%s hook for %s", h.phase, h.pkg)
+}
+
+// hooksFor lists the hooks for one phase of an operation on pkgs, whose
+// bookmarks are under mgr as config.Package.Manager has it. Global
+// hooks run once per operation, wrapped around the packages' own: before
+// them for pre hooks, after them for post hooks.
+func hooksFor(cfg *config.Config, mgr, phase string, pkgs []string) []hook {
+	var hooks []hook
+	for _, pkg := range pkgs {
+		if cmd := cfg.PackageHooks(mgr, pkg).Hook(phase); cmd != "" {
+			hooks = append(hooks, hook{phase: phase, pkg: pkg, command: cmd})
+		}
+	}
+	if cmd := cfg.Hooks.Hook(phase); cmd != "" {
+		global := hook{phase: phase, command: cmd}
+		if strings.HasPrefix(phase, "pre_") {
+			hooks = append([]hook{global}, hooks...)
+		} else {
+			hooks = append(hooks, global)
+		}
+	}
+	return hooks
+}
+
+// runHook runs h with sh. The hook learns what's happening from
+// BOXY_PHASE, BOXY_MANAGER, BOXY_PACKAGES (space-separated) and, for a
+// bookmark's own hook, BOXY_PACKAGE.
+func runHook(h hook, mgr string, pkgs []string) commandOutputMsg {
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.command)
+	// Don't wait on anything the hook left running that holds its output
+	cmd.WaitDelay = time.Second
+	cmd.Env = append(os.Environ(),
+		"BOXY_PHASE="+h.phase,
+		"BOXY_MANAGER="+mgr,
+		"BOXY_PACKAGES="+strings.Join(pkgs, " "),
+	)
+	if h.pkg != "" {
+		cmd.Env = append(cmd.Env, "BOXY_PACKAGE="+h.pkg)
+	}
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("%s timed out after %v", h, hookTimeout)
+	} else if err != nil {
+		err = fmt.Errorf("%s failed: %w", h, err)
+	}
+	return commandOutputMsg{
+		command: fmt.Sprintf("%s  # %s", h.command, h),
+		output:  string(output),
+		err:     err,
+	}
+}
+
+// operation is one manager command with the config's hooks around it
+type operation struct {
+	mgr    manager.PackageManager
+	action string
+	pkgs   []string // as the hooks see them
+	args   []string // as the command gets them, which may pin versions
+	pre    []hook
+	post   []hook
+}
+
+// operation gathers the hooks for action on pkgs. The hooks are read here,
+// on the Update goroutine, rather than when the operation runs.
+func (m Model) operation(mgr manager.PackageManager, action string, pkgs []string) operation {
+	return operation{
+		mgr:    mgr,
+		action: action,
+		pkgs:   pkgs,
+		args:   pkgs,
+		pre:    hooksFor(m.cfg, m.bookmarkManager(mgr.Name()), "pre_"+action, pkgs),
+		post:   hooksFor(m.cfg, m.bookmarkManager(mgr.Name()), "post_"+action, pkgs),
+	}
+}
+
+// run runs the pre hooks, the command and the post hooks, adding the
+// output of each to steps. A failing pre hook stops the operation and is
+// its error; failing post hooks are returned apart, as the command itself
+// went through. The command is recorded in the history by itself, or as
+// part of recording if that's set.
+func (op operation) run(password string, recording *manager.Recording, steps *[]commandOutputMsg) (hookErrs []error, err error) {
+	for _, h := range op.pre {
+		out := runHook(h, op.mgr.Name(), op.pkgs)
+		*steps = append(*steps, out)
+		if out.err != nil {
+			return nil, out.err
+		}
+	}
+
+	out := execCommand(op.mgr, op.action, op.args, password, recording)
+	*steps = append(*steps, out)
+	if out.err != nil {
+		return nil, out.err
+	}
+
+	for _, h := range op.post {
+		out := runHook(h, op.mgr.Name(), op.pkgs)
+		*steps = append(*steps, out)
+		if out.err != nil {
+			hookErrs = append(hookErrs, out.err)
+		}
+	}
+	return hookErrs, nil
+}
+
+// withHookErrs wraps result to report failed post hooks, if there were any
+func withHookErrs(result tea.Msg, errs []error) tea.Msg {
+	if len(errs) == 0 {
+		return result
+	}
+	return hookFailedMsg{errs: errs, result: result}
+}
+
+// chainOutput links the outputs of several commands so each one reaches
+// the log in turn, followed by result
+func chainOutput(steps []commandOutputMsg, result tea.Msg) tea.Msg {
+	for i := len(steps) - 1; i >= 0; i-- {
+		steps[i].result = result
+		result = steps[i]
+	}
+	return result
+}
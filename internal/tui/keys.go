@@ -1,19 +1,52 @@
 package tui
 
-import "github.com/charmbracelet/bubbles/key"
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"boxy/internal/config"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
 
 type keyMap struct {
-	Up        key.Binding
-	Down      key.Binding
-	Install   key.Binding
-	Uninstall key.Binding
-	Bookmark  key.Binding
-	Info      key.Binding
-	Search    key.Binding
-	Quit      key.Binding
-	Escape    key.Binding
-	Confirm   key.Binding
-	Cancel    key.Binding
+	Up              key.Binding
+	Down            key.Binding
+	PageUp          key.Binding
+	PageDown        key.Binding
+	Top             key.Binding
+	Bottom          key.Binding
+	Install         key.Binding
+	Uninstall       key.Binding
+	Upgrade         key.Binding
+	Bookmark        key.Binding
+	Tags            key.Binding
+	TagFilter       key.Binding
+	Note            key.Binding
+	Info            key.Binding
+	Files           key.Binding
+	Search          key.Binding
+	View            key.Binding
+	Sort            key.Binding
+	FilterInstalled key.Binding
+	FilterNoise     key.Binding
+	FilterManager   key.Binding
+	Palette         key.Binding
+	History         key.Binding
+	Undo            key.Binding
+	Snapshots       key.Binding
+	Help            key.Binding
+	Quit            key.Binding
+	Escape          key.Binding
+	Confirm         key.Binding
+	Cancel          key.Binding
+	Save            key.Binding
+
+	// Submit ends text entry. It isn't configurable, so rebinding info
+	// can't leave the search bar or a prompt without a way to submit.
+	Submit key.Binding
 }
 
 func defaultKeyMap() keyMap {
@@ -26,6 +59,22 @@ func defaultKeyMap() keyMap {
 			key.WithKeys("down", "j"),
 			key.WithHelp("↓/j", "down"),
 		),
+		PageUp: key.NewBinding(
+			key.WithKeys("pgup"),
+			key.WithHelp("PgUp", "page up"),
+		),
+		PageDown: key.NewBinding(
+			key.WithKeys("pgdown", " "),
+			key.WithHelp("PgDn", "page down"),
+		),
+		Top: key.NewBinding(
+			key.WithKeys("home"),
+			key.WithHelp("Home", "top"),
+		),
+		Bottom: key.NewBinding(
+			key.WithKeys("end"),
+			key.WithHelp("End", "bottom"),
+		),
 		Install: key.NewBinding(
 			key.WithKeys("i"),
 			key.WithHelp("i", "install"),
@@ -34,18 +83,78 @@ func defaultKeyMap() keyMap {
 			key.WithKeys("u"),
 			key.WithHelp("u", "uninstall"),
 		),
+		Upgrade: key.NewBinding(
+			key.WithKeys("+"),
+			key.WithHelp("+", "upgrade"),
+		),
 		Bookmark: key.NewBinding(
 			key.WithKeys("b"),
 			key.WithHelp("b", "bookmark"),
 		),
+		Tags: key.NewBinding(
+			key.WithKeys("t"),
+			key.WithHelp("t", "tags"),
+		),
+		TagFilter: key.NewBinding(
+			key.WithKeys("T"),
+			key.WithHelp("T", "next tag"),
+		),
+		Note: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "note"),
+		),
 		Info: key.NewBinding(
 			key.WithKeys("enter"),
 			key.WithHelp("Enter", "info"),
 		),
+		Files: key.NewBinding(
+			key.WithKeys("f"),
+			key.WithHelp("f", "files"),
+		),
 		Search: key.NewBinding(
 			key.WithKeys("/"),
 			key.WithHelp("/", "search"),
 		),
+		View: key.NewBinding(
+			key.WithKeys("v"),
+			key.WithHelp("v", "view"),
+		),
+		Sort: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "sort"),
+		),
+		FilterInstalled: key.NewBinding(
+			key.WithKeys("I"),
+			key.WithHelp("I", "installed/not"),
+		),
+		FilterNoise: key.NewBinding(
+			key.WithKeys("L"),
+			key.WithHelp("L", "hide lib/dev/dbg"),
+		),
+		FilterManager: key.NewBinding(
+			key.WithKeys("M"),
+			key.WithHelp("M", "manager"),
+		),
+		Palette: key.NewBinding(
+			key.WithKeys(":", "ctrl+p"),
+			key.WithHelp(":", "commands"),
+		),
+		History: key.NewBinding(
+			key.WithKeys("H"),
+			key.WithHelp("H", "history"),
+		),
+		Undo: key.NewBinding(
+			key.WithKeys("U"),
+			key.WithHelp("U", "undo"),
+		),
+		Snapshots: key.NewBinding(
+			key.WithKeys("S"),
+			key.WithHelp("S", "snapshots"),
+		),
+		Help: key.NewBinding(
+			key.WithKeys("?"),
+			key.WithHelp("?", "help"),
+		),
 		Quit: key.NewBinding(
 			key.WithKeys("q"),
 			key.WithHelp("q", "quit"),
@@ -62,5 +171,232 @@ func defaultKeyMap() keyMap {
 			key.WithKeys("n"),
 			key.WithHelp("n", "no"),
 		),
+		Save: key.NewBinding(
+			key.WithKeys("ctrl+s"),
+			key.WithHelp("ctrl+s", "save"),
+		),
+		Submit: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("Enter", "submit"),
+		),
+	}
+}
+
+// vimKeyMap adds vim motions to the defaults
+func vimKeyMap() keyMap {
+	k := defaultKeyMap()
+	setKeys(&k.PageUp, "ctrl+b", "ctrl+u", "pgup")
+	setKeys(&k.PageDown, "ctrl+f", "ctrl+d", "pgdown", " ")
+	setKeys(&k.Top, "g", "home")
+	setKeys(&k.Bottom, "G", "end")
+	setKeys(&k.Uninstall, "x", "u")
+	return k
+}
+
+// emacsKeyMap uses control and meta chords for movement, which also work
+// while typing in the search bar
+func emacsKeyMap() keyMap {
+	k := defaultKeyMap()
+	setKeys(&k.Up, "ctrl+p", "up")
+	setKeys(&k.Down, "ctrl+n", "down")
+	setKeys(&k.PageUp, "alt+v", "pgup")
+	setKeys(&k.PageDown, "ctrl+v", "pgdown")
+	setKeys(&k.Top, "alt+<", "home")
+	setKeys(&k.Bottom, "alt+>", "end")
+	setKeys(&k.Search, "ctrl+s", "/")
+	setKeys(&k.Escape, "ctrl+g", "esc")
+	setKeys(&k.Quit, "q", "ctrl+c")
+	// ctrl+p moves up, so the palette gets M-x
+	setKeys(&k.Palette, "alt+x", ":")
+	return k
+}
+
+var keyPresets = map[string]func() keyMap{
+	"default": defaultKeyMap,
+	"vim":     vimKeyMap,
+	"emacs":   emacsKeyMap,
+}
+
+// setKeys rebinds b, deriving the help key from the keys
+func setKeys(b *key.Binding, keys ...string) {
+	b.SetKeys(keys...)
+	b.SetHelp(helpKey(keys), b.Help().Desc)
+}
+
+func helpKey(keys []string) string {
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		switch k {
+		case "up":
+			names[i] = "↑"
+		case "down":
+			names[i] = "↓"
+		case " ":
+			names[i] = "space"
+		case "enter":
+			names[i] = "Enter"
+		case "esc":
+			names[i] = "Esc"
+		case "pgup":
+			names[i] = "PgUp"
+		case "pgdown":
+			names[i] = "PgDn"
+		case "home":
+			names[i] = "Home"
+		case "end":
+			names[i] = "End"
+		default:
+			names[i] = k
+		}
+	}
+	return strings.Join(names, "/")
+}
+
+// actions maps the action names used in the keys: config section to their
+// bindings
+func (k *keyMap) actions() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"up":               &k.Up,
+		"down":             &k.Down,
+		"page_up":          &k.PageUp,
+		"page_down":        &k.PageDown,
+		"top":              &k.Top,
+		"bottom":           &k.Bottom,
+		"install":          &k.Install,
+		"uninstall":        &k.Uninstall,
+		"upgrade":          &k.Upgrade,
+		"bookmark":         &k.Bookmark,
+		"tags":             &k.Tags,
+		"tag_filter":       &k.TagFilter,
+		"note":             &k.Note,
+		"info":             &k.Info,
+		"files":            &k.Files,
+		"search":           &k.Search,
+		"view":             &k.View,
+		"sort":             &k.Sort,
+		"filter_installed": &k.FilterInstalled,
+		"filter_noise":     &k.FilterNoise,
+		"filter_manager":   &k.FilterManager,
+		"palette":          &k.Palette,
+		"history":          &k.History,
+		"undo":             &k.Undo,
+		"snapshots":        &k.Snapshots,
+		"help":             &k.Help,
+		"quit":             &k.Quit,
+		"escape":           &k.Escape,
+		"confirm":          &k.Confirm,
+		"cancel":           &k.Cancel,
+		"save":             &k.Save,
+	}
+}
+
+// bindings is actions plus the fixed bindings, for checking conflicts
+func (k *keyMap) bindings() map[string]*key.Binding {
+	bindings := k.actions()
+	bindings["submit"] = &k.Submit
+	return bindings
+}
+
+// keyContexts lists the actions that are live at the same time, so they
+// can't share a key. Actions grouped together do the same thing in that
+// context (Esc, Enter and q all close the info modal) and may overlap.
+// Text-entry modes are listed too, so nothing else can take submit's Enter.
+var keyContexts = map[string][][]string{
+	"list": {
+		{"up"}, {"down"}, {"page_up"}, {"page_down"}, {"top"}, {"bottom"},
+		{"install"}, {"uninstall"}, {"upgrade"}, {"bookmark"}, {"tags"}, {"tag_filter"},
+		{"note"}, {"info"}, {"search"}, {"view"}, {"sort"},
+		{"filter_installed"}, {"filter_noise"}, {"filter_manager"},
+		{"palette"}, {"history"}, {"undo"}, {"snapshots"}, {"help"}, {"quit"},
+		{"escape"},
+	},
+	"info": {
+		{"up"}, {"down"}, {"page_up"}, {"page_down"}, {"top"}, {"bottom"},
+		{"files"}, {"escape", "info", "quit"},
+	},
+	"confirm": {
+		{"confirm"}, {"cancel", "escape"},
+	},
+	"note": {
+		{"save"}, {"escape"},
+	},
+	"snapshots": {
+		{"up"}, {"down"}, {"info"}, {"save"}, {"escape"},
+	},
+	"search": {
+		{"up"}, {"down"}, {"page_up"}, {"page_down"}, {"search"}, {"submit"}, {"escape"},
+	},
+	"sudo": {
+		{"submit"}, {"escape"},
+	},
+	"tags": {
+		{"submit"}, {"escape"},
+	},
+	"palette": {
+		{"up"}, {"down"}, {"submit"}, {"escape"},
+	},
+}
+
+// loadKeyMap builds the key map from the config's preset and overrides,
+// rejecting unknown presets and actions and keys bound to two actions
+// that are live at the same time.
+func loadKeyMap(cfg config.Keys) (keyMap, error) {
+	preset := cfg.Preset
+	if preset == "" {
+		preset = "default"
+	}
+	newKeyMap, ok := keyPresets[preset]
+	if !ok {
+		return keyMap{}, fmt.Errorf("unknown keys preset %q (want default, vim or emacs)", preset)
+	}
+	k := newKeyMap()
+
+	actions := k.actions()
+	for name, keys := range cfg.Bindings {
+		b, ok := actions[name]
+		if !ok {
+			return keyMap{}, fmt.Errorf("unknown key action %q", name)
+		}
+		setKeys(b, keys...)
+	}
+
+	if err := k.checkConflicts(); err != nil {
+		return keyMap{}, err
+	}
+	return k, nil
+}
+
+func (k *keyMap) checkConflicts() error {
+	actions := k.bindings()
+	contexts := make([]string, 0, len(keyContexts))
+	for name := range keyContexts {
+		contexts = append(contexts, name)
+	}
+	sort.Strings(contexts)
+
+	for _, context := range contexts {
+		owner := make(map[string]string) // key -> first action bound to it
+		group := make(map[string]int)
+		for g, names := range keyContexts[context] {
+			for _, name := range names {
+				for _, k := range actions[name].Keys() {
+					if prev, ok := owner[k]; ok && group[k] != g {
+						return fmt.Errorf("key %q is bound to both %s and %s", k, prev, name)
+					}
+					owner[k] = name
+					group[k] = g
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// matchesNav is key.Matches for text-entry modes: printable keys are left
+// for typing, so only keys like arrows, Enter and ctrl chords match.
+func matchesNav(msg tea.KeyMsg, b ...key.Binding) bool {
+	if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+		return false
 	}
+	return key.Matches(msg, b...)
 }
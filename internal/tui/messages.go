@@ -1,8 +1,16 @@
 package tui
 
-import "boxy/internal/manager"
+import (
+	"boxy/internal/config"
+	"boxy/internal/history"
+	"boxy/internal/manager"
+	"boxy/internal/snapshot"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
 
 type packagesLoadedMsg struct {
+	mgr        string
 	bookmarked []manager.PackageInfo
 	installed  []manager.PackageInfo
 	manual     []manager.PackageInfo
@@ -10,26 +18,114 @@ type packagesLoadedMsg struct {
 }
 
 type searchResultsMsg struct {
+	gen     int
 	results []manager.PackageInfo
 	err     error
 }
 
+type searchDebounceMsg struct {
+	gen int
+}
+
 type packageInfoMsg struct {
 	info manager.PackageInfo
 	err  error
 }
 
+type packageFilesMsg struct {
+	pkg   string
+	files []string
+	err   error
+}
+
 type installResultMsg struct {
+	mgr     string
+	pkg     string
+	caveats string
+	err     error
+}
+
+type uninstallResultMsg struct {
+	mgr string
 	pkg string
 	err error
 }
 
-type uninstallResultMsg struct {
+type upgradeResultMsg struct {
 	pkg string
 	err error
 }
 
+type tagInstallResultMsg struct {
+	tag  string
+	pkgs []pkgKey
+	err  error
+}
+
+type undoResultMsg struct {
+	label string // the operation undone
+	err   error
+}
+
+type snapshotComparedMsg struct {
+	name string
+	plan snapshot.Plan
+	err  error
+}
+
+type snapshotSavedMsg struct {
+	name string
+	err  error
+}
+
+type snapshotRestoredMsg struct {
+	name      string
+	bookmarks []config.Package // the snapshot's, if they differ from the current ones
+	err       error
+}
+
+type cleanupResultMsg struct {
+	err error
+}
+
+// hookFailedMsg reports post hooks that failed after an operation
+// succeeded, once its result has been handled
+type hookFailedMsg struct {
+	errs   []error
+	result tea.Msg
+}
+
+// commandOutputMsg carries a finished command's output to the log, then
+// its result message on to Update
+type commandOutputMsg struct {
+	command    string
+	output     string
+	err        error
+	historyErr error // recording the operation in the history failed
+	result     tea.Msg
+}
+
+type historyLoadedMsg struct {
+	entries []history.Entry
+	err     error
+}
+
 type bookmarkToggledMsg struct {
 	pkg        string
 	bookmarked bool
 }
+
+type indexReadyMsg struct {
+	mgr   string
+	index *manager.Index
+}
+
+type detailDebounceMsg struct {
+	gen int
+}
+
+type packageDetailMsg struct {
+	pkg  string
+	info manager.PackageInfo
+	err  error
+}
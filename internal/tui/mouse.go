@@ -0,0 +1,128 @@
+package tui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// listTopRow is the screen row of the first package row: View renders
+	// the title, the rule, the search bar, a blank line, the section header
+	// and the column header above it.
+	listTopRow      = 6
+	wheelStep       = 3
+	doubleClickTime = 400 * time.Millisecond
+)
+
+func (m *Model) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	switch m.viewMode {
+	case viewNormal, viewSearch:
+		cmd = m.handleListMouse(msg)
+
+	case viewInfo:
+		switch msg.Button {
+		case tea.MouseButtonWheelUp:
+			m.scrollInfo(-wheelStep)
+		case tea.MouseButtonWheelDown:
+			m.scrollInfo(wheelStep)
+		}
+
+	case viewConfirm:
+		if msg.Button != tea.MouseButtonLeft || msg.Action != tea.MouseActionPress {
+			break
+		}
+		yes, no := m.confirmButtons()
+		switch {
+		case m.clickedOn(msg, yes):
+			cmd = m.confirm()
+		case m.clickedOn(msg, no):
+			m.cancelConfirm()
+		}
+	}
+	return m, tea.Batch(cmd, m.followCursor())
+}
+
+// handleListMouse moves the cursor to a clicked row, opens info on a
+// double click, and scrolls the list with the wheel. The search bar keeps
+// focus, so typing carries on after a click.
+func (m *Model) handleListMouse(msg tea.MouseMsg) tea.Cmd {
+	if msg.X >= m.listWidth() || m.searching && m.filtered == nil {
+		// In the detail panel, or the list is hidden behind the spinner
+		return nil
+	}
+
+	switch {
+	case msg.Button == tea.MouseButtonWheelUp:
+		m.scrollList(-wheelStep)
+
+	case msg.Button == tea.MouseButtonWheelDown:
+		m.scrollList(wheelStep)
+
+	case msg.Button == tea.MouseButtonLeft && msg.Action == tea.MouseActionPress:
+		row := msg.Y - listTopRow
+		if row == -2 && m.filtered == nil {
+			// The section header, which holds the tag strip
+			if tag, ok := m.tagTabAt(msg.X); ok {
+				m.setTagFilter(tag)
+			}
+			return nil
+		}
+		idx := m.scroll + row
+		if row < 0 || row >= m.maxVisibleItems() || idx >= len(m.visibleItems()) {
+			return nil
+		}
+		double := idx == m.lastClickRow && time.Since(m.lastClickAt) < doubleClickTime
+		m.cursor = idx
+		m.lastClickRow, m.lastClickAt = idx, time.Now()
+		if !double {
+			return nil
+		}
+		m.lastClickAt = time.Time{}
+		if m.viewMode == viewSearch {
+			m.viewMode = viewNormal
+			m.searchInput.Blur()
+		}
+		return m.showInfo()
+	}
+	return nil
+}
+
+// scrollList scrolls the viewport, dragging the cursor along when it would
+// leave the screen
+func (m *Model) scrollList(delta int) {
+	items := m.visibleItems()
+	maxVisible := m.maxVisibleItems()
+	m.scroll = max(min(m.scroll+delta, len(items)-maxVisible), 0)
+	m.cursor = max(min(m.cursor, m.scroll+maxVisible-1), m.scroll)
+	m.cursor = min(m.cursor, max(len(items)-1, 0))
+}
+
+// confirmButtons returns the yes and no buttons of the confirm modal
+func (m Model) confirmButtons() (string, string) {
+	return fmt.Sprintf("[%s] Yes", m.keys.Confirm.Help().Key), fmt.Sprintf("[%s] No", m.keys.Cancel.Help().Key)
+}
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[a-zA-Z]`)
+
+// clickedOn reports whether a click landed on label as currently rendered.
+// Modals are laid out by lipgloss, so the label is found in the rendered
+// screen rather than computed.
+func (m Model) clickedOn(msg tea.MouseMsg, label string) bool {
+	lines := strings.Split(ansiEscape.ReplaceAllString(m.View(), ""), "\n")
+	if msg.Y < 0 || msg.Y >= len(lines) {
+		return false
+	}
+	line := lines[msg.Y]
+	i := strings.Index(line, label)
+	if i < 0 {
+		return false
+	}
+	start := lipgloss.Width(line[:i])
+	return msg.X >= start && msg.X < start+lipgloss.Width(label)
+}
@@ -0,0 +1,80 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+func newNoteInput() textarea.Model {
+	ta := textarea.New()
+	ta.Placeholder = "Why is this package here?"
+	ta.ShowLineNumbers = false
+	ta.Prompt = ""
+	ta.CharLimit = 1000
+	ta.FocusedStyle.CursorLine = lipgloss.NewStyle()
+	ta.SetWidth(modalContentWidth)
+	ta.SetHeight(5)
+	return ta
+}
+
+// editNote opens the note editor for the package under the cursor
+func (m *Model) editNote() tea.Cmd {
+	item, ok := m.selected()
+	if !ok {
+		return nil
+	}
+	m.notePkg = keyOf(item.info)
+	m.noteInput.SetValue(m.cfg.Note(m.bookmarkKey(m.notePkg)))
+	m.viewMode = viewNote
+	return m.noteInput.Focus()
+}
+
+func (m *Model) handleNoteKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case matchesNav(msg, m.keys.Save):
+		m.saveNote()
+		return m, nil
+
+	case matchesNav(msg, m.keys.Escape):
+		m.closeNote()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.noteInput, cmd = m.noteInput.Update(msg)
+	return m, cmd
+}
+
+func (m *Model) closeNote() {
+	m.viewMode = viewNormal
+	m.noteInput.Blur()
+	m.notePkg = pkgKey{}
+}
+
+// saveNote stores the edited note, bookmarking the package like tagging
+// does
+func (m *Model) saveNote() {
+	key := m.notePkg
+	m.closeNote()
+
+	mgr, pkg := m.bookmarkKey(key)
+	m.cfg.SetNote(mgr, pkg, m.noteInput.Value())
+	if err := m.cfg.Save(); err != nil {
+		m.statusMsg = fmt.Sprintf("Saving note failed: %v", err)
+		m.statusErr = true
+		return
+	}
+	m.updateBookmarkStatus(key, true)
+	m.updateBookmarkMeta(key)
+	m.mergeBookmarkedItems()
+
+	if m.cfg.Note(mgr, pkg) == "" {
+		m.statusMsg = fmt.Sprintf("Removed note for %s", pkg)
+	} else {
+		m.statusMsg = fmt.Sprintf("Saved note for %s", pkg)
+	}
+	m.statusErr = false
+}
@@ -0,0 +1,239 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"boxy/internal/manager"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// The command palette keeps the best paletteMaxMatches matches and shows
+// paletteRows of them at a time
+const (
+	paletteMaxMatches = 100
+	paletteRows       = 10
+)
+
+// paletteEntry is an action or package offered by the command palette
+type paletteEntry struct {
+	label   string
+	hint    string // key binding for actions, description for packages
+	isPkg   bool
+	perform func(m *Model) tea.Cmd
+}
+
+type paletteMatch struct {
+	entry     paletteEntry
+	positions []int
+	score     int
+}
+
+func (m *Model) openPalette() tea.Cmd {
+	m.viewMode = viewPalette
+	m.palette = m.paletteEntries()
+	m.paletteInput.SetValue("")
+	m.paletteInput.Focus()
+	m.filterPalette()
+	return textinput.Blink
+}
+
+func (m *Model) closePalette() {
+	m.viewMode = viewNormal
+	m.paletteInput.Blur()
+	m.palette = nil
+	m.matches = nil
+}
+
+// paletteEntries lists the actions available for the current selection,
+// followed by every package in the lists
+func (m Model) paletteEntries() []paletteEntry {
+	action := func(label string, b key.Binding, perform func(m *Model) tea.Cmd) paletteEntry {
+		return paletteEntry{label: label, hint: b.Help().Key, perform: perform}
+	}
+	do := func(f func(m *Model)) func(m *Model) tea.Cmd {
+		return func(m *Model) tea.Cmd {
+			f(m)
+			return nil
+		}
+	}
+	none := key.Binding{}
+
+	var entries []paletteEntry
+	if item, ok := m.selected(); ok {
+		name := item.info.Name
+		if item.info.Installed {
+			entries = append(entries,
+				action("Uninstall "+name, m.keys.Uninstall, do((*Model).requestUninstall)),
+				action("Upgrade "+name, m.keys.Upgrade, do((*Model).requestUpgrade)),
+			)
+		} else {
+			entries = append(entries, action("Install "+name, m.keys.Install, do((*Model).requestInstall)))
+		}
+		bookmark := "Bookmark " + name
+		if item.bookmarked {
+			bookmark = "Remove bookmark " + name
+		}
+		entries = append(entries,
+			action(bookmark, m.keys.Bookmark, (*Model).toggleBookmark),
+			action("Show info for "+name, m.keys.Info, (*Model).showInfo),
+			action("Edit tags for "+name, m.keys.Tags, (*Model).editTags),
+			action("Edit note for "+name, m.keys.Note, (*Model).editNote),
+		)
+	}
+
+	if m.tagFilter != "" {
+		entries = append(entries, action("Show all tags", none, do(func(m *Model) { m.setTagFilter("") })))
+	}
+	for _, tag := range m.cfg.AllTags() {
+		tag := tag
+		if tag != m.tagFilter {
+			entries = append(entries, action("Show tag "+tag, none, do(func(m *Model) { m.setTagFilter(tag) })))
+		}
+		entries = append(entries, action("Install everything tagged "+tag, none, do(func(m *Model) {
+			m.requestInstallTag(tag)
+		})))
+	}
+
+	views := []string{"bookmarked", "manual", "all"}
+	entries = append(entries,
+		action(fmt.Sprintf("Switch view to %s", views[(m.viewFilter+1)%3]), m.keys.View, do((*Model).cycleView)),
+		action(fmt.Sprintf("Sort by %s", (m.sortOrder+1)%numSortOrders), m.keys.Sort, do((*Model).cycleSort)),
+		action("Export list to file", none, do((*Model).exportList)),
+		action("Refresh package lists", none, (*Model).refresh),
+		action("Clean up unneeded packages", none, do((*Model).requestCleanup)),
+	)
+	for _, mgr := range m.managers {
+		// The user-level tools are always listed with the current manager
+		if mgr.Name() != m.mgr.Name() && !manager.IsUserLevel(mgr) {
+			mgr := mgr
+			entries = append(entries, action("Switch manager to "+mgr.Name(), none, func(m *Model) tea.Cmd {
+				return m.switchManager(mgr)
+			}))
+		}
+	}
+	entries = append(entries,
+		action("Open log", none, do((*Model).openLog)),
+		action("Show history", m.keys.History, (*Model).openHistory),
+		action("Undo last operation", m.keys.Undo, do((*Model).requestUndo)),
+		action("Show snapshots", m.keys.Snapshots, (*Model).openSnapshots),
+		action("Save snapshot", none, (*Model).saveSnapshot),
+		action("Help", m.keys.Help, do(func(m *Model) { m.viewMode = viewHelp })),
+		action("Quit", m.keys.Quit, func(*Model) tea.Cmd { return tea.Quit }),
+	)
+
+	seen := make(map[string]bool)
+	for _, list := range [][]packageItem{m.filtered, m.items} {
+		for _, item := range list {
+			name := item.info.Name
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			entries = append(entries, paletteEntry{
+				label: name,
+				hint:  item.info.Description,
+				isPkg: true,
+				perform: func(m *Model) tea.Cmd {
+					m.jumpTo(name)
+					return nil
+				},
+			})
+		}
+	}
+	return entries
+}
+
+// filterPalette fuzzy-matches the query against the entries. With no query
+// only the actions are listed; packages need at least a letter.
+func (m *Model) filterPalette() {
+	query := strings.TrimSpace(m.paletteInput.Value())
+	m.matches = m.matches[:0]
+	for _, entry := range m.palette {
+		if query == "" {
+			if !entry.isPkg {
+				m.matches = append(m.matches, paletteMatch{entry: entry})
+			}
+			continue
+		}
+		score, positions, ok := fuzzyMatch(entry.label, query)
+		if !ok {
+			continue
+		}
+		if !entry.isPkg {
+			// Actions outrank packages matching as well
+			score *= nameWeight
+		}
+		m.matches = append(m.matches, paletteMatch{entry: entry, positions: positions, score: score})
+	}
+	sort.SliceStable(m.matches, func(i, j int) bool {
+		return m.matches[i].score > m.matches[j].score
+	})
+	if len(m.matches) > paletteMaxMatches {
+		m.matches = m.matches[:paletteMaxMatches]
+	}
+	m.paletteCursor = 0
+}
+
+func (m *Model) handlePaletteKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case matchesNav(msg, m.keys.Escape):
+		m.closePalette()
+		return m, nil
+
+	case matchesNav(msg, m.keys.Up):
+		m.paletteCursor = max(m.paletteCursor-1, 0)
+		return m, nil
+
+	case matchesNav(msg, m.keys.Down):
+		m.paletteCursor = min(m.paletteCursor+1, max(len(m.matches)-1, 0))
+		return m, nil
+
+	case matchesNav(msg, m.keys.Submit):
+		if m.paletteCursor >= len(m.matches) {
+			return m, nil
+		}
+		entry := m.matches[m.paletteCursor].entry
+		m.closePalette()
+		return m, entry.perform(m)
+	}
+
+	prev := m.paletteInput.Value()
+	var cmd tea.Cmd
+	m.paletteInput, cmd = m.paletteInput.Update(msg)
+	if m.paletteInput.Value() != prev {
+		m.filterPalette()
+	}
+	return m, cmd
+}
+
+func (m Model) renderPalette(bg string) string {
+	const labelWidth = 32
+	hintWidth := modalContentWidth - labelWidth - 4
+
+	var b strings.Builder
+	b.WriteString(m.paletteInput.View())
+	b.WriteString("\n")
+	if len(m.matches) == 0 {
+		b.WriteString("\n")
+		b.WriteString(dimStyle.Render("No matches"))
+	}
+	start := max(m.paletteCursor-paletteRows+1, 0)
+	for i := start; i < len(m.matches) && i < start+paletteRows; i++ {
+		match := m.matches[i]
+		b.WriteString("\n")
+		prefix, style := "  ", normalStyle
+		if i == m.paletteCursor {
+			prefix, style = "> ", selectedStyle
+		}
+		b.WriteString(prefix)
+		b.WriteString(renderMatches(match.entry.label, labelWidth, match.positions, style))
+		b.WriteString("  ")
+		b.WriteString(dimStyle.Render(truncate(match.entry.hint, hintWidth)))
+	}
+	return m.overlayModal(bg, "Commands", b.String(), plainHelp(m.helpKeys().ShortHelp()...))
+}
@@ -0,0 +1,99 @@
+package tui
+
+import (
+	"sort"
+	"strings"
+)
+
+type installedFilter int
+
+const (
+	installedAny installedFilter = iota
+	installedOnly
+	notInstalledOnly
+)
+
+// searchFilters narrow the remote search results. They are kept for the
+// whole session, so they survive clearing and re-running searches.
+type searchFilters struct {
+	installed installedFilter
+	hideNoise bool   // hide lib*, *-dev and *-dbg packages
+	source    string // only show this manager's results; "" for all
+}
+
+// apply returns the items that pass the filters
+func (f searchFilters) apply(items []packageItem) []packageItem {
+	if f == (searchFilters{}) {
+		return items
+	}
+	visible := make([]packageItem, 0, len(items))
+	for _, item := range items {
+		if f.installed == installedOnly && !item.info.Installed {
+			continue
+		}
+		if f.installed == notInstalledOnly && item.info.Installed {
+			continue
+		}
+		if f.hideNoise && isNoisePackage(item.info.Name) {
+			continue
+		}
+		if f.source != "" && item.info.Manager != f.source {
+			continue
+		}
+		visible = append(visible, item)
+	}
+	return visible
+}
+
+// labels describes the active filters for the results header
+func (f searchFilters) labels() []string {
+	var labels []string
+	switch f.installed {
+	case installedOnly:
+		labels = append(labels, "installed")
+	case notInstalledOnly:
+		labels = append(labels, "not installed")
+	}
+	if f.hideNoise {
+		labels = append(labels, "no lib/dev/dbg")
+	}
+	if f.source != "" {
+		labels = append(labels, f.source)
+	}
+	return labels
+}
+
+// isNoisePackage reports whether name looks like a library, development
+// or debug symbols package, which drown out applications in search results
+func isNoisePackage(name string) bool {
+	return strings.HasPrefix(name, "lib") ||
+		strings.HasSuffix(name, "-dev") ||
+		strings.HasSuffix(name, "-dbg")
+}
+
+// nextSource cycles the source filter through the managers present in
+// items, then back to all
+func nextSource(current string, items []packageItem) string {
+	seen := make(map[string]bool)
+	var sources []string
+	for _, item := range items {
+		if src := item.info.Manager; src != "" && !seen[src] {
+			seen[src] = true
+			sources = append(sources, src)
+		}
+	}
+	sort.Strings(sources)
+
+	if current == "" {
+		if len(sources) > 0 {
+			return sources[0]
+		}
+		return ""
+	}
+	for i, src := range sources {
+		if src == current && i+1 < len(sources) {
+			return sources[i+1]
+		}
+	}
+	return ""
+}
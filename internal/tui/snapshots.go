@@ -0,0 +1,222 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"boxy/internal/config"
+	"boxy/internal/snapshot"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// The snapshots screen shows snapshotRows snapshots at a time, and the
+// first snapshotPlanRows changes restoring the selected one would make
+const (
+	snapshotRows     = 8
+	snapshotPlanRows = 12
+)
+
+// openSnapshots lists the saved snapshots, comparing the newest with the
+// machine
+func (m *Model) openSnapshots() tea.Cmd {
+	snapshots, err := snapshot.List()
+	if err != nil {
+		m.statusMsg = fmt.Sprintf("Error reading snapshots: %v", err)
+		m.statusErr = true
+		return nil
+	}
+	m.snapshots = snapshots
+	m.snapshotIdx = 0
+	m.viewMode = viewSnapshots
+	return m.compareSnapshot()
+}
+
+func (m *Model) closeSnapshots() {
+	m.viewMode = viewNormal
+	m.snapshots = nil
+	m.snapshotPlan = nil
+	m.snapshotErr = nil
+}
+
+func (m Model) selectedSnapshot() (*snapshot.Snapshot, bool) {
+	if m.snapshotIdx >= len(m.snapshots) {
+		return nil, false
+	}
+	return m.snapshots[m.snapshotIdx], true
+}
+
+// compareSnapshot works out in the background what restoring the selected
+// snapshot would change
+func (m *Model) compareSnapshot() tea.Cmd {
+	m.snapshotPlan = nil
+	m.snapshotErr = nil
+	s, ok := m.selectedSnapshot()
+	if !ok {
+		return nil
+	}
+	managers := m.managers
+	cfg := &config.Config{Packages: m.cfg.Packages}
+	return func() tea.Msg {
+		plan, err := snapshot.Compare(context.Background(), s, managers, cfg)
+		return snapshotComparedMsg{name: s.Name, plan: plan, err: err}
+	}
+}
+
+func (m *Model) handleSnapshotsKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Escape):
+		m.closeSnapshots()
+
+	case key.Matches(msg, m.keys.Up):
+		if m.snapshotIdx > 0 {
+			m.snapshotIdx--
+			return m, m.compareSnapshot()
+		}
+
+	case key.Matches(msg, m.keys.Down):
+		if m.snapshotIdx < len(m.snapshots)-1 {
+			m.snapshotIdx++
+			return m, m.compareSnapshot()
+		}
+
+	case key.Matches(msg, m.keys.Info):
+		m.requestRestore()
+
+	case key.Matches(msg, m.keys.Save):
+		return m, m.saveSnapshot()
+	}
+	return m, nil
+}
+
+// saveSnapshot records the machine's packages under a name taken from the
+// time
+func (m *Model) saveSnapshot() tea.Cmd {
+	name := time.Now().Format("2006-01-02-150405")
+	managers := m.managers
+	cfg := &config.Config{Packages: m.cfg.Packages}
+	m.statusMsg = "Saving snapshot..."
+	m.statusErr = false
+	return func() tea.Msg {
+		s, err := snapshot.Take(context.Background(), name, managers, cfg)
+		if err == nil {
+			err = s.Save()
+		}
+		return snapshotSavedMsg{name: name, err: err}
+	}
+}
+
+// requestRestore asks to restore the selected snapshot, once it's been
+// compared with the machine
+func (m *Model) requestRestore() {
+	s, ok := m.selectedSnapshot()
+	if !ok || m.snapshotPlan == nil {
+		return
+	}
+	if m.snapshotPlan.Empty() {
+		m.statusMsg = fmt.Sprintf("Already matches snapshot %s", s.Name)
+		m.statusErr = false
+		return
+	}
+	m.restorePlan = *m.snapshotPlan
+	m.closeSnapshots()
+	m.confirmPkg = ""
+	m.confirmAct = confirmRestore
+	m.viewMode = viewConfirm
+}
+
+// restoreSnapshot runs the plan's commands in order, with the hooks for
+// each, stopping at the first that fails, then puts the snapshot's
+// bookmarks back. Versions the plan couldn't restore fail it at the end.
+func (m Model) restoreSnapshot(plan snapshot.Plan, password string) tea.Cmd {
+	planOps := plan.Ops()
+	var ops, fallbacks []operation
+	for _, op := range planOps {
+		// Hooks see the names, not the pinned versions
+		pinned := m.operation(op.Manager, op.Action, op.Names)
+		pinned.args = op.Pkgs
+		ops = append(ops, pinned)
+		fallbacks = append(fallbacks, m.operation(op.Manager, op.Action, op.Fallback))
+	}
+	return func() tea.Msg {
+		var steps []commandOutputMsg
+		var hookErrs []error
+		run := func(op operation) error {
+			errs, err := op.run(password, nil, &steps)
+			hookErrs = append(hookErrs, errs...)
+			return err
+		}
+
+		var err error
+		for i, op := range ops {
+			err = run(op)
+			if err != nil && len(fallbacks[i].pkgs) > 0 {
+				if fallbackErr := run(fallbacks[i]); fallbackErr != nil {
+					err = fallbackErr
+				} else {
+					err = planOps[i].FallbackError(err)
+				}
+			}
+			if err != nil {
+				break
+			}
+		}
+
+		result := snapshotRestoredMsg{name: plan.Snapshot.Name, err: err}
+		if err == nil && plan.Bookmarks {
+			result.bookmarks = plan.Snapshot.Bookmarks
+		}
+		if err == nil {
+			result.err = plan.UnpinnedError()
+		}
+		return chainOutput(steps, withHookErrs(result, hookErrs))
+	}
+}
+
+func (m Model) renderSnapshots(bg string) string {
+	var b strings.Builder
+	if len(m.snapshots) == 0 {
+		b.WriteString(dimStyle.Render(fmt.Sprintf("No snapshots yet. %s saves one.", m.keys.Save.Help().Key)))
+		return m.renderWithModal(bg, "Snapshots", b.String())
+	}
+
+	start := max(m.snapshotIdx-snapshotRows+1, 0)
+	for i := start; i < len(m.snapshots) && i < start+snapshotRows; i++ {
+		s := m.snapshots[i]
+		prefix, style := "  ", normalStyle
+		if i == m.snapshotIdx {
+			prefix, style = "> ", selectedStyle
+		}
+		if i > start {
+			b.WriteString("\n")
+		}
+		b.WriteString(prefix)
+		b.WriteString(style.Render(fmt.Sprintf("%-18s", truncate(s.Name, 18))))
+		b.WriteString(dimStyle.Render(truncate(fmt.Sprintf("  %-12s  %s", s.Created.Local().Format("Jan 2 15:04"), s.Summary()), modalContentWidth-20)))
+	}
+	b.WriteString("\n\n")
+
+	s := m.snapshots[m.snapshotIdx]
+	switch {
+	case m.snapshotErr != nil:
+		b.WriteString(errorStyle.Render(fmt.Sprintf("Error comparing: %v", m.snapshotErr)))
+	case m.snapshotPlan == nil:
+		b.WriteString(dimStyle.Render("Comparing with the installed packages..."))
+	case m.snapshotPlan.Empty():
+		b.WriteString(fmt.Sprintf("The machine matches %s", s.Name))
+	default:
+		b.WriteString(fmt.Sprintf("Restoring %s would:", s.Name))
+		lines := m.snapshotPlan.Lines()
+		for i, line := range lines {
+			if i == snapshotPlanRows {
+				b.WriteString(fmt.Sprintf("\n  ... and %d more", len(lines)-snapshotPlanRows))
+				break
+			}
+			b.WriteString("\n  " + truncate(line, modalContentWidth-2))
+		}
+	}
+	return m.renderWithModal(bg, "Snapshots", b.String())
+}
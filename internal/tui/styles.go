@@ -2,54 +2,94 @@ package tui
 
 import "github.com/charmbracelet/lipgloss"
 
+// modalContentWidth is the text width inside modalStyle (width minus padding)
+const modalContentWidth = 56
+
 var (
+	titleStyle        lipgloss.Style
+	managerStyle      lipgloss.Style
+	headerStyle       lipgloss.Style
+	selectedStyle     lipgloss.Style
+	normalStyle       lipgloss.Style
+	dimStyle          lipgloss.Style
+	installedStyle    lipgloss.Style
+	notInstalledStyle lipgloss.Style
+	bookmarkStyle     lipgloss.Style
+	helpStyle         lipgloss.Style
+	helpKeyStyle      lipgloss.Style
+	modalStyle        lipgloss.Style
+	detailStyle       lipgloss.Style
+	errorStyle        lipgloss.Style
+	successStyle      lipgloss.Style
+	searchStyle       lipgloss.Style
+	matchStyle        lipgloss.Style
+)
+
+func init() {
+	applyTheme(darkTheme)
+}
+
+// applyTheme rebuilds the styles from a theme's palette
+func applyTheme(t theme) {
 	titleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("205")).
-			Padding(0, 1)
+		Bold(true).
+		Foreground(t.Accent).
+		Padding(0, 1)
 
 	managerStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("240")).
-			Padding(0, 1)
+		Foreground(t.Muted).
+		Padding(0, 1)
 
 	headerStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("99"))
+		Bold(true).
+		Foreground(t.Heading)
 
 	selectedStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("205")).
-			Bold(true)
+		Foreground(t.Accent).
+		Bold(true)
 
 	normalStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("252"))
+		Foreground(t.Text)
 
 	dimStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("240"))
+		Foreground(t.Muted)
 
 	installedStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("42"))
+		Foreground(t.Success)
 
 	notInstalledStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("240"))
+		Foreground(t.Muted)
 
 	bookmarkStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("214"))
+		Foreground(t.Bookmark)
 
 	helpStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("241"))
+		Foreground(t.Help)
+
+	helpKeyStyle = lipgloss.NewStyle().
+		Foreground(t.HelpKey)
 
 	modalStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("99")).
-			Padding(1, 2).
-			Width(60)
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.Border).
+		Padding(1, 2).
+		Width(60)
+
+	detailStyle = lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.PanelBorder).
+		Padding(0, 1)
 
 	errorStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("196"))
+		Foreground(t.Error)
 
 	successStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("42"))
+		Foreground(t.Success)
 
 	searchStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("99"))
-)
+		Foreground(t.Heading)
+
+	matchStyle = lipgloss.NewStyle().
+		Foreground(t.Match).
+		Bold(true)
+}
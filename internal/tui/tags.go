@@ -0,0 +1,282 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"boxy/internal/manager"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// editTags opens the tag editor for the package under the cursor
+func (m *Model) editTags() tea.Cmd {
+	item, ok := m.selected()
+	if !ok {
+		return nil
+	}
+	m.tagsPkg = keyOf(item.info)
+	m.tagInput.SetValue(strings.Join(m.cfg.Tags(m.bookmarkKey(m.tagsPkg)), ", "))
+	m.tagInput.CursorEnd()
+	m.tagInput.Focus()
+	m.viewMode = viewTags
+	return textinput.Blink
+}
+
+func (m *Model) handleTagsKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case matchesNav(msg, m.keys.Submit):
+		m.saveTags()
+		return m, nil
+
+	case matchesNav(msg, m.keys.Escape):
+		m.closeTags()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.tagInput, cmd = m.tagInput.Update(msg)
+	return m, cmd
+}
+
+func (m *Model) closeTags() {
+	m.viewMode = viewNormal
+	m.tagInput.Blur()
+	m.tagsPkg = pkgKey{}
+}
+
+// saveTags stores the edited tags. Tags live on bookmarks, so tagging a
+// package bookmarks it.
+func (m *Model) saveTags() {
+	key := m.tagsPkg
+	m.closeTags()
+
+	mgr, pkg := m.bookmarkKey(key)
+	tags := strings.FieldsFunc(m.tagInput.Value(), func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	m.cfg.SetTags(mgr, pkg, tags)
+	if err := m.cfg.Save(); err != nil {
+		m.statusMsg = fmt.Sprintf("Saving tags failed: %v", err)
+		m.statusErr = true
+		return
+	}
+	tags = m.cfg.Tags(mgr, pkg)
+	m.updateBookmarkStatus(key, true)
+	m.updateBookmarkMeta(key)
+	m.mergeBookmarkedItems()
+
+	if m.tagFilter != "" && len(m.cfg.Tagged(m.tagFilter)) == 0 {
+		// The last package with the tag lost it
+		m.setTagFilter("")
+	}
+	if len(tags) == 0 {
+		m.statusMsg = fmt.Sprintf("Cleared tags for %s", pkg)
+	} else {
+		m.statusMsg = fmt.Sprintf("Tagged %s: %s", pkg, strings.Join(tags, ", "))
+	}
+	m.statusErr = false
+}
+
+// updateBookmarkMeta refreshes the tags and note the lists show for key
+func (m *Model) updateBookmarkMeta(key pkgKey) {
+	for i := range m.items {
+		if keyOf(m.items[i].info) == key {
+			m.items[i].tags = m.cfg.Tags(m.bookmarkKey(key))
+			m.items[i].note = m.cfg.Note(m.bookmarkKey(key))
+			break
+		}
+	}
+	for i := range m.filtered {
+		if keyOf(m.filtered[i].info) == key {
+			m.filtered[i].tags = m.cfg.Tags(m.bookmarkKey(key))
+			m.filtered[i].note = m.cfg.Note(m.bookmarkKey(key))
+			break
+		}
+	}
+	m.listChanged()
+}
+
+// cycleTagFilter steps the list through each tag in use, then back to
+// showing every tag
+func (m *Model) cycleTagFilter() {
+	tags := m.cfg.AllTags()
+	next := ""
+	if m.tagFilter == "" && len(tags) > 0 {
+		next = tags[0]
+	}
+	for i, tag := range tags {
+		if tag == m.tagFilter && i+1 < len(tags) {
+			next = tags[i+1]
+		}
+	}
+	m.setTagFilter(next)
+}
+
+func (m *Model) setTagFilter(tag string) {
+	m.tagFilter = tag
+	m.cursor = 0
+	m.scroll = 0
+}
+
+// hasTag reports whether item passes the tag filter
+func (m Model) hasTag(item packageItem) bool {
+	if m.tagFilter == "" {
+		return true
+	}
+	for _, tag := range item.tags {
+		if tag == m.tagFilter {
+			return true
+		}
+	}
+	return false
+}
+
+// requestInstallTag asks to install every package tagged tag that isn't
+// installed yet, as one command per manager. Bookmarks of tools that
+// aren't available are left out.
+func (m *Model) requestInstallTag(tag string) {
+	installed := make(map[pkgKey]bool)
+	for _, item := range m.items {
+		installed[keyOf(item.info)] = item.info.Installed
+	}
+	var pkgs []pkgKey
+	for _, p := range m.cfg.Tagged(tag) {
+		key := pkgKey{mgr: p.Manager, name: p.Name}
+		if key.mgr == "" {
+			key.mgr = m.mgr.Name()
+		}
+		if done, listed := installed[key]; listed && !done {
+			pkgs = append(pkgs, key)
+		}
+	}
+	if len(pkgs) == 0 {
+		m.statusMsg = fmt.Sprintf("Everything tagged %s is installed", tag)
+		m.statusErr = false
+		return
+	}
+	m.confirmPkg = tag
+	m.confirmPkgs = pkgs
+	m.confirmAct = confirmInstallTag
+	m.viewMode = viewConfirm
+}
+
+// installTag installs pkgs with the managers they're listed under, one
+// command per manager, stopping at the first that fails
+func (m Model) installTag(tag string, pkgs []pkgKey, password string) tea.Cmd {
+	ops := m.installOps(pkgs)
+	return func() tea.Msg {
+		var steps []commandOutputMsg
+		var hookErrs []error
+		var err error
+		for _, op := range ops {
+			var errs []error
+			errs, err = op.run(password, nil, &steps)
+			hookErrs = append(hookErrs, errs...)
+			if err != nil {
+				break
+			}
+		}
+		result := tagInstallResultMsg{tag: tag, pkgs: pkgs, err: err}
+		return chainOutput(steps, withHookErrs(result, hookErrs))
+	}
+}
+
+// installOps groups keys by the manager each is listed under into one
+// install per manager
+func (m Model) installOps(keys []pkgKey) []operation {
+	var order []manager.PackageManager
+	groups := make(map[string][]string)
+	for _, key := range keys {
+		mgr := m.managerNamed(key.mgr)
+		if _, ok := groups[mgr.Name()]; !ok {
+			order = append(order, mgr)
+		}
+		groups[mgr.Name()] = append(groups[mgr.Name()], key.name)
+	}
+	ops := make([]operation, len(order))
+	for i, mgr := range order {
+		ops[i] = m.operation(mgr, "install", groups[mgr.Name()])
+	}
+	return ops
+}
+
+// confirmTarget describes what the pending action applies to
+func (m Model) confirmTarget() string {
+	switch m.confirmAct {
+	case confirmCleanup:
+		return "unneeded packages"
+	case confirmInstallTag:
+		return "everything tagged " + m.confirmPkg
+	case confirmUndo:
+		return undoLabel(m.undoPlan.entry)
+	case confirmRestore:
+		return "snapshot " + m.restorePlan.Snapshot.Name
+	}
+	return m.confirmPkg
+}
+
+// confirmDetail spells out what the confirm modal's target covers
+func (m Model) confirmDetail() string {
+	switch m.confirmAct {
+	case confirmCleanup:
+		return m.cleanupCmd
+	case confirmInstallTag:
+		return strings.Join(keyNames(m.confirmPkgs), ", ")
+	case confirmUndo:
+		return m.undoPlan.String()
+	case confirmRestore:
+		return m.restorePlan.Summary()
+	}
+	return ""
+}
+
+// tagStripLabel starts the tag strip in the list header
+const tagStripLabel = "  tags:"
+
+// renderTagStrip renders the tags in use as tabs, the active one in
+// brackets, or nothing if no bookmark is tagged
+func (m Model) renderTagStrip() string {
+	tags := m.cfg.AllTags()
+	if len(tags) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(dimStyle.Render(tagStripLabel))
+	for _, tag := range append([]string{""}, tags...) {
+		label := tag
+		if tag == "" {
+			label = "all"
+		}
+		if tag == m.tagFilter {
+			b.WriteString(selectedStyle.Render("[" + label + "]"))
+		} else {
+			b.WriteString(dimStyle.Render(" " + label + " "))
+		}
+	}
+	return b.String()
+}
+
+// tagTabAt returns the tag of the tab at column x of the list header, if
+// the click landed on one. "" is the "all" tab.
+func (m Model) tagTabAt(x int) (string, bool) {
+	header := ansiEscape.ReplaceAllString(m.listHeader(len(m.visibleItems())), "")
+	start := strings.Index(header, tagStripLabel)
+	if start < 0 {
+		return "", false
+	}
+	pos := lipgloss.Width(header[:start]) + len(tagStripLabel)
+	for _, tag := range append([]string{""}, m.cfg.AllTags()...) {
+		width := len(tag) + 2
+		if tag == "" {
+			width = len("all") + 2
+		}
+		if x >= pos && x < pos+width {
+			return tag, true
+		}
+		pos += width
+	}
+	return "", false
+}
@@ -0,0 +1,181 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// theme is a palette of colors by role. Colors are ANSI 256 numbers or
+// #rrggbb hex, as accepted by lipgloss.Color.
+type theme struct {
+	Accent      lipgloss.Color // title, selected row
+	Text        lipgloss.Color // package names
+	Muted       lipgloss.Color // versions, descriptions, hints
+	Heading     lipgloss.Color // section headers, search bar
+	Success     lipgloss.Color // installed marks, success messages
+	Bookmark    lipgloss.Color
+	Error       lipgloss.Color
+	Match       lipgloss.Color // fuzzy match highlights
+	Border      lipgloss.Color // modal border
+	PanelBorder lipgloss.Color // detail panel border
+	Help        lipgloss.Color
+	HelpKey     lipgloss.Color
+}
+
+var darkTheme = theme{
+	Accent:      "205",
+	Text:        "252",
+	Muted:       "240",
+	Heading:     "99",
+	Success:     "42",
+	Bookmark:    "214",
+	Error:       "196",
+	Match:       "81",
+	Border:      "99",
+	PanelBorder: "240",
+	Help:        "241",
+	HelpKey:     "247",
+}
+
+var lightTheme = theme{
+	Accent:      "162",
+	Text:        "235",
+	Muted:       "244",
+	Heading:     "55",
+	Success:     "28",
+	Bookmark:    "166",
+	Error:       "160",
+	Match:       "25",
+	Border:      "55",
+	PanelBorder: "248",
+	Help:        "243",
+	HelpKey:     "238",
+}
+
+// highContrastTheme sticks to the 16 basic colors at full intensity, which
+// every terminal palette keeps readable
+var highContrastTheme = theme{
+	Accent:      "11",
+	Text:        "15",
+	Muted:       "7",
+	Heading:     "14",
+	Success:     "10",
+	Bookmark:    "11",
+	Error:       "9",
+	Match:       "13",
+	Border:      "15",
+	PanelBorder: "7",
+	Help:        "7",
+	HelpKey:     "15",
+}
+
+var builtinThemes = map[string]theme{
+	"dark":          darkTheme,
+	"light":         lightTheme,
+	"high-contrast": highContrastTheme,
+}
+
+// roles maps the color names used in the themes: config section to the
+// palette's fields
+func (t *theme) roles() map[string]*lipgloss.Color {
+	return map[string]*lipgloss.Color{
+		"accent":       &t.Accent,
+		"text":         &t.Text,
+		"muted":        &t.Muted,
+		"heading":      &t.Heading,
+		"success":      &t.Success,
+		"bookmark":     &t.Bookmark,
+		"error":        &t.Error,
+		"match":        &t.Match,
+		"border":       &t.Border,
+		"panel_border": &t.PanelBorder,
+		"help":         &t.Help,
+		"help_key":     &t.HelpKey,
+	}
+}
+
+// loadTheme resolves the configured theme name against the built-in themes
+// and the user's palettes, and applies it. An empty name or "auto" picks
+// dark or light from the terminal background. User palettes start from
+// their "base" theme (auto by default) and override colors by role.
+//
+// With NO_COLOR set the palette is left empty so no colors are emitted, and
+// fuzzy matches are underlined since they can't be colored.
+func loadTheme(name string, palettes map[string]map[string]string) error {
+	t, err := resolveTheme(name, palettes, make(map[string]bool))
+	if err != nil {
+		return err
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		applyTheme(theme{})
+		matchStyle = matchStyle.Underline(true)
+		return nil
+	}
+	applyTheme(t)
+	return nil
+}
+
+func resolveTheme(name string, palettes map[string]map[string]string, seen map[string]bool) (theme, error) {
+	if name == "" || name == "auto" {
+		if lipgloss.HasDarkBackground() {
+			return darkTheme, nil
+		}
+		return lightTheme, nil
+	}
+	if t, ok := builtinThemes[name]; ok {
+		return t, nil
+	}
+	palette, ok := palettes[name]
+	if !ok {
+		return theme{}, fmt.Errorf("unknown theme %q (want auto, %s or one from themes:)", name, strings.Join(builtinThemeNames(), ", "))
+	}
+	if seen[name] {
+		return theme{}, fmt.Errorf("theme %q: base themes form a cycle", name)
+	}
+	seen[name] = true
+
+	t, err := resolveTheme(palette["base"], palettes, seen)
+	if err != nil {
+		return theme{}, err
+	}
+	roles := t.roles()
+	for role, color := range palette {
+		if role == "base" {
+			continue
+		}
+		field, ok := roles[role]
+		if !ok {
+			return theme{}, fmt.Errorf("theme %q: unknown color %q", name, role)
+		}
+		if !validColor(color) {
+			return theme{}, fmt.Errorf("theme %q: %s: %q is not an ANSI color number or #rrggbb", name, role, color)
+		}
+		*field = lipgloss.Color(color)
+	}
+	return t, nil
+}
+
+func builtinThemeNames() []string {
+	var names []string
+	for name := range builtinThemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+func validColor(s string) bool {
+	if hexColor.MatchString(s) {
+		return true
+	}
+	n, err := strconv.Atoi(s)
+	return err == nil && n >= 0 && n <= 255
+}
@@ -0,0 +1,197 @@
+package tui
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"boxy/internal/manager"
+)
+
+// toolSearchTimeout bounds how long a search waits for the tools, which
+// mostly ask registries online
+const toolSearchTimeout = 5 * time.Second
+
+// userTools returns the user-level managers, like pipx and uv, other than
+// mgr. Their packages are listed, searched and installed alongside mgr's.
+func userTools(managers []manager.PackageManager, mgr manager.PackageManager) []manager.PackageManager {
+	var tools []manager.PackageManager
+	for _, tool := range managers {
+		if tool.Name() != mgr.Name() && manager.IsUserLevel(tool) {
+			tools = append(tools, tool)
+		}
+	}
+	return tools
+}
+
+// preferredManager is the first manager that isn't user-level, so the
+// list is the system's packages with the tools mixed in
+func preferredManager(managers []manager.PackageManager) manager.PackageManager {
+	for _, mgr := range managers {
+		if !manager.IsUserLevel(mgr) {
+			return mgr
+		}
+	}
+	return managers[0]
+}
+
+// managerNamed returns the tool called name, or the current manager for
+// its own packages and anything else
+func (m Model) managerNamed(name string) manager.PackageManager {
+	for _, tool := range m.tools {
+		if tool.Name() == name {
+			return tool
+		}
+	}
+	return m.mgr
+}
+
+// pkgKey identifies an item in the merged list, where a tool's package
+// may share its name with one of the current manager's
+type pkgKey struct {
+	mgr  string
+	name string
+}
+
+// keyOf is the key of a listed package, whose Manager is always set
+func keyOf(info manager.PackageInfo) pkgKey {
+	return pkgKey{mgr: info.Manager, name: info.Name}
+}
+
+// keyNames lists the names of keys, for messages
+func keyNames(keys []pkgKey) []string {
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = key.name
+	}
+	return names
+}
+
+// bookmarkManager is the manager a bookmark of one of mgr's packages
+// records: the tool for a user-level tool's, or "" for the system
+// manager's, as config.Package has it
+func (m Model) bookmarkManager(mgr string) string {
+	if mgr != "" && manager.IsUserLevel(m.managerNamed(mgr)) {
+		return mgr
+	}
+	return ""
+}
+
+// bookmarkKey is the key for key's bookmark in the config
+func (m Model) bookmarkKey(key pkgKey) (mgr, name string) {
+	return m.bookmarkManager(key.mgr), key.name
+}
+
+// managerOf returns the manager pkg is listed under, preferring the
+// selected item when two managers have a package of that name
+func (m Model) managerOf(pkg string) manager.PackageManager {
+	if item, ok := m.selected(); ok && item.info.Name == pkg {
+		return m.managerNamed(item.info.Manager)
+	}
+	for _, list := range [][]packageItem{m.filtered, m.items} {
+		for _, item := range list {
+			if item.info.Name == pkg {
+				return m.managerNamed(item.info.Manager)
+			}
+		}
+	}
+	return m.mgr
+}
+
+// managerLabel names the current manager and the tools listed with it
+func (m Model) managerLabel() string {
+	names := []string{m.mgr.Name()}
+	for _, tool := range m.tools {
+		names = append(names, tool.Name())
+	}
+	return strings.Join(names, " + ")
+}
+
+// toolLists returns a tool's installed and manually installed packages,
+// or ok false to leave the tool out
+type toolLists func(tool manager.PackageManager) (installed, manual []manager.PackageInfo, ok bool)
+
+// withTools adds the tools' packages to the current manager's lists,
+// marking each with the tool it came from
+func withTools(installed, manual []manager.PackageInfo, tools []manager.PackageManager, lists toolLists) ([]manager.PackageInfo, []manager.PackageInfo) {
+	if len(tools) == 0 {
+		return installed, manual
+	}
+	// Copy rather than append, so the cache's slices are left alone
+	installed = append([]manager.PackageInfo(nil), installed...)
+	manual = append([]manager.PackageInfo(nil), manual...)
+	for _, tool := range tools {
+		toolInstalled, toolManual, ok := lists(tool)
+		if !ok {
+			continue
+		}
+		installed = append(installed, markManager(toolInstalled, tool.Name())...)
+		manual = append(manual, markManager(toolManual, tool.Name())...)
+	}
+	return installed, manual
+}
+
+func markManager(infos []manager.PackageInfo, name string) []manager.PackageInfo {
+	marked := make([]manager.PackageInfo, len(infos))
+	for i, info := range infos {
+		info.Manager = name
+		marked[i] = info
+	}
+	return marked
+}
+
+// listTools lists each tool afresh. A tool that fails to list is left out
+// rather than failing the whole list.
+func listTools(ctx context.Context) toolLists {
+	return func(tool manager.PackageManager) ([]manager.PackageInfo, []manager.PackageInfo, bool) {
+		installed, err := tool.ListInstalled(ctx)
+		if err != nil {
+			return nil, nil, false
+		}
+		manual, _ := tool.ListManuallyInstalled(ctx)
+		return installed, manual, true
+	}
+}
+
+// cachedTools lists the tools from the last run, for those that kept them
+func cachedTools(tool manager.PackageManager) ([]manager.PackageInfo, []manager.PackageInfo, bool) {
+	cached, ok := tool.(*manager.CachedManager)
+	if !ok {
+		return nil, nil, false
+	}
+	return cached.Cached()
+}
+
+// searchTools searches the tools that can search, in parallel, and sends
+// what they found once all have answered. A tool that fails or runs out of
+// time is left out rather than failing the search.
+func searchTools(ctx context.Context, tools []manager.PackageManager, query string) <-chan []manager.PackageInfo {
+	done := make(chan []manager.PackageInfo, 1)
+	ctx, cancel := context.WithTimeout(ctx, toolSearchTimeout)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var results []manager.PackageInfo
+	for _, tool := range tools {
+		if !manager.CanSearch(tool) {
+			continue
+		}
+		wg.Add(1)
+		go func(tool manager.PackageManager) {
+			defer wg.Done()
+			found, err := searchManager(ctx, tool, query)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			results = append(results, found...)
+			mu.Unlock()
+		}(tool)
+	}
+	go func() {
+		wg.Wait()
+		cancel()
+		done <- results
+	}()
+	return done
+}
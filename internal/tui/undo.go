@@ -0,0 +1,188 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"boxy/internal/history"
+	"boxy/internal/manager"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// undoPlan reverses one recorded operation
+type undoPlan struct {
+	entry   history.Entry
+	remove  []string         // installed by the operation
+	restore []history.Change // removed or changed by it, put back at Before
+}
+
+func planUndo(e history.Entry) undoPlan {
+	plan := undoPlan{entry: e}
+	for _, c := range e.Packages {
+		switch {
+		case !c.Changed():
+		case c.Before == "":
+			plan.remove = append(plan.remove, c.Name)
+		default:
+			plan.restore = append(plan.restore, c)
+		}
+	}
+	return plan
+}
+
+// changed lists the packages whose version the operation changed, at the
+// versions to put back
+func (p undoPlan) changed() []string {
+	var changed []string
+	for _, c := range p.restore {
+		if c.After != "" {
+			changed = append(changed, c.Name+" "+c.Before)
+		}
+	}
+	return changed
+}
+
+// pkgs names every package the undo acts on
+func (p undoPlan) pkgs() []string {
+	pkgs := append([]string(nil), p.remove...)
+	for _, c := range p.restore {
+		pkgs = append(pkgs, c.Name)
+	}
+	return pkgs
+}
+
+func (p undoPlan) String() string {
+	var parts []string
+	if len(p.remove) > 0 {
+		parts = append(parts, "remove "+strings.Join(p.remove, ", "))
+	}
+	if len(p.restore) > 0 {
+		restore := make([]string, len(p.restore))
+		for i, c := range p.restore {
+			restore[i] = c.Name + " " + c.Before
+		}
+		parts = append(parts, "reinstall "+strings.Join(restore, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// requestUndo asks to reverse the most recent operation boxy ran with the
+// current manager or the tools listed with it that hasn't been undone yet
+func (m *Model) requestUndo() {
+	own, err := history.Load()
+	if err != nil {
+		m.statusMsg = fmt.Sprintf("Error reading history: %v", err)
+		m.statusErr = true
+		return
+	}
+	names := []string{m.mgr.Name()}
+	for _, tool := range m.tools {
+		names = append(names, tool.Name())
+	}
+	entry, ok := history.LastUndoable(history.Merge(own), names...)
+	if !ok {
+		m.statusMsg = "Nothing to undo"
+		m.statusErr = false
+		return
+	}
+	plan := planUndo(entry)
+	mgr := m.managerNamed(entry.Manager)
+	if changed := plan.changed(); len(changed) > 0 && !manager.CanPin(mgr) {
+		// Installing the package again would leave it at its current
+		// version and look like it had worked
+		m.statusMsg = fmt.Sprintf("Can't undo %s: %s can't reinstall %s", undoLabel(entry), mgr.Name(), strings.Join(changed, ", "))
+		m.statusErr = true
+		return
+	}
+	m.undoPlan = plan
+	m.confirmPkg = ""
+	m.confirmMgr = mgr
+	m.confirmAct = confirmUndo
+	m.viewMode = viewConfirm
+}
+
+// undoLabel names the operation an undo reverses
+func undoLabel(e history.Entry) string {
+	return fmt.Sprintf("the %s at %s", e.Action, e.Time.Local().Format("Jan 2 15:04"))
+}
+
+// undoOps are the operations an undo runs, with their hooks
+type undoOps struct {
+	remove    operation // what the operation installed
+	reinstall operation // what it removed or changed, at the earlier versions
+	fallback  operation // what it removed, unpinned, if reinstall pins versions
+	changed   bool      // reinstall includes version changes, which can't fall back
+}
+
+// undoOps works out the operations for plan. Managers that can install
+// old versions get the recorded ones. If those are gone, packages the
+// operation removed fall back to the current version, which at least puts
+// them back; one whose version it changed has nothing to fall back to.
+func (m Model) undoOps(mgr manager.PackageManager, plan undoPlan) undoOps {
+	ops := undoOps{remove: m.operation(mgr, "uninstall", plan.remove)}
+	names := make([]string, len(plan.restore))
+	pinned := make([]string, len(plan.restore))
+	pins := false
+	var removed []string
+	for i, c := range plan.restore {
+		names[i] = c.Name
+		pinned[i] = manager.Pinned(mgr, c.Name, c.Before)
+		pins = pins || pinned[i] != c.Name
+		if c.After == "" {
+			removed = append(removed, c.Name)
+		} else {
+			ops.changed = true
+		}
+	}
+	ops.reinstall = m.operation(mgr, "install", names)
+	ops.reinstall.args = pinned
+	if pins {
+		ops.fallback = m.operation(mgr, "install", removed)
+	}
+	return ops
+}
+
+// undo removes what the operation installed, then reinstalls what it
+// removed or changed. A reinstall that can't restore every version fails
+// the undo, after putting back what it can. The steps are recorded as one
+// history entry, so the operation only counts as undone if all of them
+// went through.
+func (m Model) undo(plan undoPlan, password string) tea.Cmd {
+	mgr := m.managerNamed(plan.entry.Manager)
+	ops := m.undoOps(mgr, plan)
+	return func() tea.Msg {
+		recording := manager.StartRecording(context.Background(), mgr)
+		var steps []commandOutputMsg
+		var hookErrs []error
+		run := func(op operation) error {
+			errs, err := op.run(password, recording, &steps)
+			hookErrs = append(hookErrs, errs...)
+			return err
+		}
+
+		var err error
+		if len(ops.remove.pkgs) > 0 {
+			err = run(ops.remove)
+		}
+		if err == nil && len(ops.reinstall.pkgs) > 0 {
+			err = run(ops.reinstall)
+			if err != nil && len(ops.fallback.pkgs) > 0 {
+				if fallbackErr := run(ops.fallback); fallbackErr != nil {
+					err = fallbackErr
+				} else if ops.changed {
+					err = fmt.Errorf("couldn't reinstall the earlier versions: %w", err)
+				} else {
+					err = nil
+				}
+			}
+		}
+		undoes := plan.entry.Time
+		if historyErr := recording.Finish("undo", plan.pkgs(), &undoes, err); historyErr != nil && len(steps) > 0 {
+			steps[len(steps)-1].historyErr = historyErr
+		}
+		result := undoResultMsg{label: undoLabel(plan.entry), err: err}
+		return chainOutput(steps, withHookErrs(result, hookErrs))
+	}
+}